@@ -31,8 +31,15 @@ func main() {
 
 	logger := logger.NewLogger(flags.Mode)
 
+	// Initializing priority policy
+	priorityPolicy, err := services.NewPriorityPolicy(cfg.Priority)
+	if err != nil {
+		logger.Error("", "config_invalid", "Priority policy config is invalid", err, nil)
+		os.Exit(1)
+	}
+
 	// Initializing repository
-	repo, err := repository.NewRepository(*cfg)
+	repo, err := repository.NewRepository(*cfg, priorityPolicy)
 	if err != nil {
 		logger.Error("", "db_connection_failed", "Database is unreachable after all retries", err, nil)
 		os.Exit(1)
@@ -47,8 +54,10 @@ func main() {
 		app.Order(ctx, logger, repo, flags, stop, *cfg)
 	case "kitchen-worker":
 		app.Kitchen(ctx, logger, repo, flags, stop, *cfg)
+	case "delivery-courier":
+		app.Courier(ctx, logger, repo, flags, stop, *cfg)
 	case "tracking-service":
-		app.Tracking(ctx, logger, repo, flags, stop)
+		app.Tracking(ctx, logger, repo, flags, stop, *cfg)
 	case "notification-subscriber":
 		app.Notification(ctx, logger, *cfg)
 	}
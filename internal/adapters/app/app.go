@@ -5,12 +5,17 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"wheres-my-pizza/internal/adapters/auth"
 	"wheres-my-pizza/internal/adapters/db/repository"
+	"wheres-my-pizza/internal/adapters/microservices/delivery"
 	"wheres-my-pizza/internal/adapters/microservices/kitchen"
 	"wheres-my-pizza/internal/adapters/microservices/notifications"
 	"wheres-my-pizza/internal/adapters/microservices/order"
 	"wheres-my-pizza/internal/adapters/microservices/tracking"
+	"wheres-my-pizza/internal/adapters/payments"
 	"wheres-my-pizza/internal/adapters/rabbitmq"
+	"wheres-my-pizza/internal/adapters/spool"
+	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/internal/core/services"
 	"wheres-my-pizza/pkg/config"
 	"wheres-my-pizza/pkg/logger"
@@ -26,19 +31,64 @@ func Order(ctx context.Context, logger *logger.Logger, repo *repository.Reposito
 	}
 	logger.Info("", "rabbitmq_connected", "Connected to RabbitMQ exchange "+"order_topic", map[string]interface{}{"duration_ms": orderRabbit.DurationMs})
 
+	// Scheduled orders rules
+	schedule, err := services.NewScheduleRules(cfg.Scheduling)
+	if err != nil {
+		logger.Error("", "config_invalid", "Scheduling config is invalid", err, nil)
+		os.Exit(1)
+	}
+
+	// Disk spool for order messages while RabbitMQ is unreachable
+	orderSpool, err := spool.New(cfg.Spool)
+	if err != nil {
+		logger.Error("", "spool_init_failed", "Cannot open the spool directory", err, map[string]interface{}{"dir": cfg.Spool.Dir})
+		os.Exit(1)
+	}
+
+	// Payment provider of online orders
+	paymentProvider, err := payments.New(cfg.Payments)
+	if err != nil {
+		logger.Error("", "config_invalid", "Payments config is invalid", err, nil)
+		os.Exit(1)
+	}
+
 	// Initializing Order-service
-	orderService := order.NewOrderHandler(repo, orderRabbit, flags.Order.MaxConcurrent, flags.Order.Port, logger)
+	orderService := order.NewOrderHandler(repo, orderRabbit, flags.Order, schedule, orderSpool, paymentProvider, logger)
+
+	// Publishing orders saved in the outbox
+	go orderService.RelayOutbox(ctx)
+
+	// Replaying the spool once RabbitMQ is back
+	go orderService.DrainSpool(ctx)
+
+	// Sending due scheduled orders to the kitchen
+	go orderService.DispatchScheduledOrders(ctx)
+
+	// Retrying refunds the payment provider did not accept yet
+	go orderService.RetryRefunds(ctx)
+
+	// API keys and bearer tokens, the menu is public
+	authn := auth.New(cfg.Auth, repo, logger)
 
 	// Initializing Mux
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /orders", orderService.PostOrder)
+	mux.HandleFunc("POST /orders", authn.Require(domain.ScopeOrdersWrite, orderService.PostOrder))
+	mux.HandleFunc("POST /orders/batch", authn.Require(domain.ScopeOrdersWrite, orderService.PostOrderBatch))
+	mux.HandleFunc("PATCH /orders/{order_number}", authn.Require(domain.ScopeOrdersWrite, orderService.ModifyOrder))
+	mux.HandleFunc("GET /menu", orderService.GetMenu)
+	mux.HandleFunc("GET /menu/modifiers", orderService.GetMenuModifiers)
+	mux.HandleFunc("POST /orders/{order_number}/cancel", authn.Require(domain.ScopeOrdersWrite, orderService.CancelOrder))
+	mux.HandleFunc("POST /orders/{order_number}/payments", authn.Require(domain.ScopeOrdersWrite, orderService.PostPayment))
+	mux.HandleFunc("POST /tables/{table_number}/close", authn.Require(domain.ScopeOrdersWrite, orderService.CloseTable))
+	mux.HandleFunc("POST /customers", authn.Require(domain.ScopeOrdersWrite, orderService.PostCustomer))
+	mux.HandleFunc("GET /customers/{id}", authn.Require(domain.ScopeOrdersRead, orderService.GetCustomer))
 	server := http.Server{
 		Addr:    fmt.Sprintf(":%d", flags.Order.Port),
 		Handler: mux,
 	}
 	// Starting server
 	go func() {
-		logger.Info("", "service_started", "Order Service started on port"+server.Addr, map[string]interface{}{"details": map[string]interface{}{"port": flags.Order.Port, "max_concurrent": flags.Order.MaxConcurrent}})
+		logger.Info("", "service_started", "Order Service started on port"+server.Addr, map[string]interface{}{"details": map[string]interface{}{"port": flags.Order.Port, "max_concurrent": flags.Order.MaxConcurrent, "max_wait_ms": flags.Order.MaxWaitMs}})
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			stop()
 			fmt.Printf("cannot start server: %v\n", err)
@@ -52,7 +102,7 @@ func Order(ctx context.Context, logger *logger.Logger, repo *repository.Reposito
 func Kitchen(ctx context.Context, logger *logger.Logger, repo *repository.Repository, flags services.Flags, stop context.CancelFunc, cfg config.Config) {
 	// Initializing rabbitmq for kitchen
 	// reconnectCh := make(chan)
-	kitchenRabbit, err := rabbitmq.NewKitchenRabbit(flags.Kitchen.OrderTypes, flags.Kitchen.WorkerName, flags.Kitchen.Prefetch, logger, cfg)
+	kitchenRabbit, err := rabbitmq.NewKitchenRabbit(flags.Kitchen.OrderTypes, flags.Kitchen.Stations, flags.Kitchen.WorkerName, flags.Kitchen.LocationID, flags.Kitchen.Prefetch, logger, cfg)
 	if err != nil {
 		// Gracefull shutdown
 		fmt.Printf("cannot connect to rabbitmq: %v\n", err)
@@ -73,16 +123,46 @@ func Kitchen(ctx context.Context, logger *logger.Logger, repo *repository.Reposi
 	kitchenService.Stop(ctx)
 }
 
-func Tracking(ctx context.Context, logger *logger.Logger, repo *repository.Repository, flags services.Flags, stop context.CancelFunc) {
+func Courier(ctx context.Context, logger *logger.Logger, repo *repository.Repository, flags services.Flags, stop context.CancelFunc, cfg config.Config) {
+	// Initializing rabbitmq for couriers
+	courierRabbit, err := rabbitmq.NewCourierRabbit(flags.Courier.CourierName, flags.Courier.LocationID, flags.Courier.Prefetch, logger, cfg)
+	if err != nil {
+		// Gracefull shutdown
+		fmt.Printf("cannot connect to rabbitmq: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Info("", "rabbitmq_connected", "Connected to RabbitMQ exchange "+"order_topic", map[string]interface{}{"duration_ms": courierRabbit.DurationMs})
+
+	// Initializing Delivery service
+	deliveryService := delivery.NewDelivery(repo, courierRabbit, flags.Courier, logger)
+	err = deliveryService.Start(ctx)
+	if err != nil {
+		fmt.Printf("cannot start delivery-courier: %v\n", err)
+		stop()
+		os.Exit(1)
+	}
+
+	deliveryService.Stop(ctx)
+}
+
+func Tracking(ctx context.Context, logger *logger.Logger, repo *repository.Repository, flags services.Flags, stop context.CancelFunc, cfg config.Config) {
 	// Initializing Order-service
 	trackingService := tracking.NewTrackingHandler(repo, flags.Order.Port, logger)
 
+	// API keys and bearer tokens, the same middleware as the order service
+	authn := auth.New(cfg.Auth, repo, logger)
+
 	// Initializing Mux
 	trackingMUX := http.NewServeMux()
 
-	trackingMUX.HandleFunc("GET /orders/{order_number}/status", trackingService.GetOrderDetails)
-	trackingMUX.HandleFunc("GET /orders/{order_number}/history", trackingService.GetOrderHistory)
-	trackingMUX.HandleFunc("GET /workers/status", trackingService.GetWorkersStatuses)
+	trackingMUX.HandleFunc("GET /orders", authn.Require(domain.ScopeOrdersRead, trackingService.SearchOrders))
+	trackingMUX.HandleFunc("GET /orders/{order_number}/status", authn.Require(domain.ScopeOrdersRead, trackingService.GetOrderDetails))
+	trackingMUX.HandleFunc("GET /orders/{order_number}/history", authn.Require(domain.ScopeOrdersRead, trackingService.GetOrderHistory))
+	trackingMUX.HandleFunc("GET /workers/status", authn.Require(domain.ScopeWorkersRead, trackingService.GetWorkersStatuses))
+	trackingMUX.HandleFunc("GET /couriers/status", authn.Require(domain.ScopeWorkersRead, trackingService.GetCouriersStatuses))
+	trackingMUX.HandleFunc("GET /tables/open", authn.Require(domain.ScopeOrdersRead, trackingService.GetOpenTables))
+	trackingMUX.HandleFunc("GET /inventory", authn.Require(domain.ScopeWorkersRead, trackingService.GetInventory))
+	trackingMUX.HandleFunc("GET /customers/{id}/orders", authn.Require(domain.ScopeOrdersRead, trackingService.GetCustomerOrders))
 
 	server := http.Server{
 		Addr:    fmt.Sprintf(":%d", flags.Order.Port),
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// signJWT builds a token from raw header and claims JSON
+func signJWT(header, claims string, secret []byte) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	hs256 := `{"alg":"HS256","typ":"JWT"}`
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  []byte
+		wantErr error
+		scopes  []string
+	}{
+		{
+			name:   "valid",
+			token:  signJWT(hs256, `{"sub":"courier-app","scope":"orders:read workers:read","exp":1800000060}`, testSecret),
+			secret: testSecret,
+			scopes: []string{"orders:read", "workers:read"},
+		},
+		{
+			name:   "valid after nbf",
+			token:  signJWT(hs256, `{"sub":"courier-app","exp":1800000060,"nbf":1800000000}`, testSecret),
+			secret: testSecret,
+			scopes: []string{},
+		},
+		{
+			name:    "not yet valid",
+			token:   signJWT(hs256, `{"sub":"courier-app","exp":1800000060,"nbf":1800000001}`, testSecret),
+			secret:  testSecret,
+			wantErr: domain.ErrInvalidToken,
+		},
+		{
+			name:    "expired",
+			token:   signJWT(hs256, `{"sub":"courier-app","exp":1800000000}`, testSecret),
+			secret:  testSecret,
+			wantErr: domain.ErrTokenExpired,
+		},
+		{
+			name:    "without exp",
+			token:   signJWT(hs256, `{"sub":"courier-app"}`, testSecret),
+			secret:  testSecret,
+			wantErr: domain.ErrInvalidToken,
+		},
+		{
+			name:    "without sub",
+			token:   signJWT(hs256, `{"exp":1800000060}`, testSecret),
+			secret:  testSecret,
+			wantErr: domain.ErrInvalidToken,
+		},
+		{
+			name:    "alg none",
+			token:   signJWT(`{"alg":"none"}`, `{"sub":"courier-app","exp":1800000060}`, testSecret),
+			secret:  testSecret,
+			wantErr: domain.ErrInvalidToken,
+		},
+		{
+			name:    "other secret",
+			token:   signJWT(hs256, `{"sub":"courier-app","exp":1800000060}`, []byte("another-secret-of-thirty-two-byte")),
+			secret:  testSecret,
+			wantErr: domain.ErrInvalidToken,
+		},
+		{
+			name:    "no secret configured",
+			token:   signJWT(hs256, `{"sub":"courier-app","exp":1800000060}`, nil),
+			secret:  nil,
+			wantErr: domain.ErrInvalidToken,
+		},
+		{
+			name:    "malformed",
+			token:   "not.a-token",
+			secret:  testSecret,
+			wantErr: domain.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifyJWT(tt.token, tt.secret, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("verifyJWT() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyJWT() error = %v", err)
+			}
+			if principal.Subject != "courier-app" || principal.Method != "jwt" {
+				t.Errorf("verifyJWT() principal = %+v", principal)
+			}
+			if !slices.Equal(principal.Scopes, tt.scopes) {
+				t.Errorf("verifyJWT() scopes = %v, want %v", principal.Scopes, tt.scopes)
+			}
+		})
+	}
+}
+
+func TestVerifyJWTTamperedClaims(t *testing.T) {
+	token := signJWT(`{"alg":"HS256"}`, `{"sub":"courier-app","scope":"orders:read","exp":1800000060}`, testSecret)
+	// Elevated claims with the signature of the original ones
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"courier-app","scope":"orders:write","exp":1800000060}`)) +
+		token[strings.LastIndex(token, "."):]
+	if _, err := verifyJWT(tampered, testSecret, time.Unix(1_800_000_000, 0)); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("verifyJWT() error = %v, want %v", err, domain.ErrInvalidToken)
+	}
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// API KEYS
+
+// GetAPIKeyPrincipal finds the active API key with the hash, unknown and revoked keys return ErrInvalidAPIKey
+func (r *Repository) GetAPIKeyPrincipal(ctx context.Context, keyHash string) (domain.Principal, error) {
+	const selectSQL = `
+		SELECT name, scopes
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL;
+	`
+	principal := domain.Principal{Method: "api_key"}
+	err := r.Conn.QueryRow(ctx, selectSQL, keyHash).Scan(&principal.Subject, &principal.Scopes)
+	if err == pgx.ErrNoRows {
+		return principal, domain.ErrInvalidAPIKey
+	}
+	return principal, err
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DELIVERY COURIERS
+func (r *Repository) InsertCourier(ctx context.Context, courierName, locationID string) error {
+	if err := checkLocation(ctx, r.Conn, locationID); err != nil {
+		return err
+	}
+	const insertSQL = `
+		INSERT INTO couriers (name, status, last_seen, location_id)
+		VALUES ($1, 'online', $2, $3);
+	`
+	_, err := r.Conn.Exec(ctx, insertSQL, courierName, time.Now().UTC(), locationID)
+	return err
+}
+
+func (r *Repository) UpdateCourierStatus(ctx context.Context, courierName, status string) error {
+	const updateSQL = `
+		UPDATE couriers
+		SET status = $1, last_seen = $2
+		WHERE name = $3;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, status, time.Now().UTC(), courierName)
+	return err
+}
+
+func (r *Repository) UpdateCourierLocation(ctx context.Context, courierName, locationID string) error {
+	if err := checkLocation(ctx, r.Conn, locationID); err != nil {
+		return err
+	}
+	const updateSQL = `
+		UPDATE couriers
+		SET location_id = $1
+		WHERE name = $2;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, locationID, courierName)
+	return err
+}
+
+func (r *Repository) GetCourierStatus(ctx context.Context, courierName string) (string, error) {
+	const selectSQL = `
+		SELECT status FROM couriers WHERE name = $1;
+	`
+	var status string
+	err := r.Conn.QueryRow(ctx, selectSQL, courierName).Scan(&status)
+	return status, err
+}
+
+func (r *Repository) UpdateCourierHeartbeat(ctx context.Context, courierName string) error {
+	const updateSQL = `
+		UPDATE couriers
+		SET last_seen = now(), status = 'online'
+		WHERE name = $1;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, courierName)
+	return err
+}
+
+// OrderIsOutForDelivery moves a ready delivery order to 'out_for_delivery'.
+// A redelivered message of an order the courier already took is accepted again.
+func (r *Repository) OrderIsOutForDelivery(ctx context.Context, courierName string, order *domain.Order) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	const updateSQL = `
+		UPDATE orders
+		SET status = 'out_for_delivery', updated_at = now()
+		WHERE id = $1 AND type = 'delivery' AND status IN ('ready', 'out_for_delivery');
+	`
+	res, err := tx.Exec(ctx, updateSQL, order.ID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return deliveryStatusError(ctx, tx, order)
+	}
+
+	const insertStatusLogSQL = `
+		INSERT INTO order_status_log (order_id, status, changed_by, notes)
+		VALUES ($1, $2, $3, $4);
+	`
+	notes := fmt.Sprintf("Picked up by courier %s", courierName)
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, "out_for_delivery", courierName, notes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	order.Status = "out_for_delivery"
+	return nil
+}
+
+// OrderIsDelivered moves an order that is out for delivery to 'delivered' and counts the courier's delivery
+func (r *Repository) OrderIsDelivered(ctx context.Context, courierName string, order *domain.Order) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	const updateOrderSQL = `
+		UPDATE orders
+		SET status = 'delivered', updated_at = now()
+		WHERE id = $1 AND status = 'out_for_delivery';
+	`
+	res, err := tx.Exec(ctx, updateOrderSQL, order.ID)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return deliveryStatusError(ctx, tx, order)
+	}
+
+	const updateCourierSQL = `
+		UPDATE couriers
+		SET deliveries_completed = deliveries_completed + 1, last_seen = now()
+		WHERE name = $1;
+	`
+	res, err = tx.Exec(ctx, updateCourierSQL, courierName)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("courier %s not found", courierName)
+	}
+
+	const insertStatusLogSQL = `
+		INSERT INTO order_status_log (order_id, status, changed_by, notes)
+		VALUES ($1, $2, $3, $4);
+	`
+	notes := fmt.Sprintf("Delivered by courier %s", courierName)
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, "delivered", courierName, notes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	order.Status = "delivered"
+	return nil
+}
+
+// deliveryStatusError explains why a delivery transition did not update the order
+func deliveryStatusError(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	var status string
+	err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1`, order.ID).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("order %d not found", order.ID)
+	} else if err != nil {
+		return err
+	}
+	switch status {
+	case "cancelled":
+		return domain.ErrOrderCancelled
+	case "delivered":
+		return domain.ErrOrderDelivered
+	}
+	return fmt.Errorf("order %d has unexpected status %s", order.ID, status)
+}
+
+// GetCouriersStatuses lists the couriers, an empty locationID matches every location
+func (r *Repository) GetCouriersStatuses(ctx context.Context, heartbeatTimeout time.Duration, locationID string) ([]map[string]interface{}, error) {
+	const q = `
+		SELECT name, status, deliveries_completed, last_seen, location_id
+		FROM couriers
+		WHERE $1 = '' OR location_id = $1
+	`
+	rows, err := r.Conn.Query(ctx, q, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var couriers []map[string]interface{}
+	now := time.Now().UTC()
+
+	for rows.Next() {
+		var name, status, location string
+		var deliveriesCompleted int
+		var lastSeen time.Time
+		if err := rows.Scan(&name, &status, &deliveriesCompleted, &lastSeen, &location); err != nil {
+			return nil, err
+		}
+
+		// Check offline threshold
+		if now.Sub(lastSeen) > heartbeatTimeout*time.Second {
+			status = "offline"
+		}
+
+		couriers = append(couriers, map[string]interface{}{
+			"courier_name":         name,
+			"status":               status,
+			"deliveries_completed": deliveriesCompleted,
+			"last_seen":            lastSeen.UTC(),
+			"location_id":          location,
+		})
+	}
+
+	return couriers, rows.Err()
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CUSTOMERS
+func (r *Repository) CreateCustomer(ctx context.Context, customer *domain.Customer) error {
+	const insertSQL = `
+		INSERT INTO customers (name, phone, email)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, updated_at;
+	`
+	err := r.Conn.QueryRow(ctx, insertSQL, customer.Name, customer.Phone, customer.Email).Scan(&customer.ID, &customer.CreatedAt, &customer.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return domain.ErrCustomerExists
+	}
+	return err
+}
+
+func (r *Repository) GetCustomer(ctx context.Context, id int) (domain.Customer, error) {
+	const selectSQL = `
+		SELECT id, created_at, updated_at, name, phone, email
+		FROM customers
+		WHERE id = $1;
+	`
+	var customer domain.Customer
+	err := r.Conn.QueryRow(ctx, selectSQL, id).Scan(&customer.ID, &customer.CreatedAt, &customer.UpdatedAt, &customer.Name, &customer.Phone, &customer.Email)
+	return customer, err
+}
+
+// GetCustomerOrders returns a page of the customer's orders, newest first, and the number of all orders.
+// pgx.ErrNoRows is returned when the customer does not exist.
+func (r *Repository) GetCustomerOrders(ctx context.Context, customerID, limit, offset int) ([]domain.CustomerOrder, int, error) {
+	var total int
+	const countSQL = `
+		SELECT (SELECT count(*) FROM orders WHERE customer_id = c.id)
+		FROM customers c
+		WHERE c.id = $1;
+	`
+	if err := r.Conn.QueryRow(ctx, countSQL, customerID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	const selectSQL = `
+		SELECT number, created_at, type, status, total_amount
+		FROM orders
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3;
+	`
+	rows, err := r.Conn.Query(ctx, selectSQL, customerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []domain.CustomerOrder{}
+	for rows.Next() {
+		var order domain.CustomerOrder
+		if err := rows.Scan(&order.OrderNumber, &order.CreatedAt, &order.Type, &order.Status, &order.TotalAmount); err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, total, rows.Err()
+}
+
+// linkCustomer sets the customer of a new order inside its transaction: an explicit customer_id must exist,
+// customer details must match a registered customer on every given field (a customer registered with
+// a phone and an email needs both) and a new customer is created when nothing matches
+func linkCustomer(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	if order.CustomerID != nil {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1)`, *order.CustomerID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return domain.ValidationErrors{{Field: "customer_id", Code: domain.CodeUnknown, Message: fmt.Sprintf("unknown customer %d", *order.CustomerID)}}
+		}
+		return nil
+	}
+	if order.Customer == nil {
+		return nil
+	}
+
+	customer := *order.Customer
+	if customer.Name == "" {
+		customer.Name = order.CustomerName
+	}
+
+	// Concurrent orders of a new customer: the insert waits for the other transaction
+	// and the conflicting row is found by the second lookup
+	const insertSQL = `
+		INSERT INTO customers (name, phone, email)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING id;
+	`
+	var id int
+	for attempt := 0; attempt < 2; attempt++ {
+		found, err := findCustomer(ctx, tx, customer)
+		if err != nil {
+			return err
+		}
+		if found != 0 {
+			id = found
+			break
+		}
+		err = tx.QueryRow(ctx, insertSQL, customer.Name, customer.Phone, customer.Email).Scan(&id)
+		if err == nil {
+			break
+		} else if err != pgx.ErrNoRows {
+			return err
+		}
+	}
+	if id == 0 {
+		return fmt.Errorf("cannot find or create the customer")
+	}
+
+	// The kitchen message only needs the id
+	order.CustomerID = &id
+	order.Customer = nil
+	return nil
+}
+
+// findCustomer returns the id of the registered customer with exactly the given phone and email, 0 when
+// neither is registered. Details that belong to someone else, or to two customers, are rejected.
+func findCustomer(ctx context.Context, tx pgx.Tx, customer domain.Customer) (int, error) {
+	const findSQL = `
+		SELECT id, phone, email FROM customers
+		WHERE phone = $1 OR email = $2;
+	`
+	rows, err := tx.Query(ctx, findSQL, customer.Phone, customer.Email)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var matches []domain.Customer
+	for rows.Next() {
+		var match domain.Customer
+		if err := rows.Scan(&match.ID, &match.Phone, &match.Email); err != nil {
+			return 0, err
+		}
+		matches = append(matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(matches) == 0 {
+		return 0, nil
+	}
+	if len(matches) > 1 || !sameContact(matches[0].Phone, customer.Phone) || !sameContact(matches[0].Email, customer.Email) {
+		return 0, domain.ValidationErrors{{Field: "customer", Code: domain.CodeNotAllowed, Message: "phone and email do not match one registered customer"}}
+	}
+	return matches[0].ID, nil
+}
+
+func sameContact(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
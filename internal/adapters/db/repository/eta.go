@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// workerHeartbeatTimeout is how long a worker counts as online after its last heartbeat
+const workerHeartbeatTimeout = 50 * time.Second
+
+// ETA
+func (r *Repository) CookSeconds(ctx context.Context, orderType, locationID string) (int, error) {
+	seconds, err := cookSeconds(ctx, r.Conn, orderType, locationID)
+	return int(seconds + 0.5), err
+}
+
+// cookSeconds is the average time from 'cooking' to 'ready' of the last orders of the type at the location.
+// Without history the configured cooking time of the type is used.
+func cookSeconds(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, orderType, locationID string,
+) (float64, error) {
+	const selectSQL = `
+		SELECT extract(epoch FROM avg(ready.changed_at - cooking.changed_at))::float8
+		FROM (
+			SELECT id FROM orders
+			WHERE type = $1 AND location_id = $2 AND completed_at IS NOT NULL
+			ORDER BY completed_at DESC
+			LIMIT 50
+		) recent
+		JOIN LATERAL (
+			SELECT min(changed_at) AS changed_at FROM order_status_log WHERE order_id = recent.id AND status = 'cooking'
+		) cooking ON true
+		JOIN LATERAL (
+			SELECT min(changed_at) AS changed_at FROM order_status_log WHERE order_id = recent.id AND status = 'ready'
+		) ready ON true;
+	`
+	var seconds *float64
+	if err := q.QueryRow(ctx, selectSQL, orderType, locationID).Scan(&seconds); err != nil {
+		return 0, err
+	}
+	if seconds == nil || *seconds <= 0 {
+		return float64(services.CookingTimeSeconds(orderType)), nil
+	}
+	return *seconds, nil
+}
+
+// kitchenLoad counts the orders cooked before the order and the workers that cook its type.
+// Orders of higher priority and older orders of the same priority are ahead of it.
+func kitchenLoad(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, order *domain.Order, createdAt time.Time,
+) (domain.KitchenLoad, error) {
+	var load domain.KitchenLoad
+	const selectOrdersSQL = `
+		SELECT
+			count(*) FILTER (WHERE status IN ('received', 'paid')
+				AND (priority > $3 OR (priority = $3 AND created_at < $4))),
+			count(*) FILTER (WHERE status = 'cooking')
+		FROM orders
+		WHERE location_id = $1 AND type = $2 AND id <> $5 AND status IN ('received', 'paid', 'cooking');
+	`
+	err := q.QueryRow(ctx, selectOrdersSQL, order.LocationID, order.Type, order.Priority, createdAt, order.ID).Scan(&load.Ahead, &load.Cooking)
+	if err != nil {
+		return load, err
+	}
+
+	const selectWorkersSQL = `
+		SELECT count(*) FROM workers
+		WHERE location_id = $1 AND status = 'online' AND last_seen > now() - make_interval(secs => $3)
+			AND $2 = ANY(string_to_array(replace(type, ' ', ''), ','));
+	`
+	err = q.QueryRow(ctx, selectWorkersSQL, order.LocationID, order.Type, workerHeartbeatTimeout.Seconds()).Scan(&load.Workers)
+	if err != nil {
+		return load, err
+	}
+
+	load.CookSeconds, err = cookSeconds(ctx, q, order.Type, order.LocationID)
+	return load, err
+}
+
+// estimateReadyAt estimates when the order is ready from its status, nil when it is ready or will not be cooked.
+// Scheduled orders are ready at their scheduled time, cooking orders one average cook duration after they started.
+func estimateReadyAt(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, order *domain.Order, createdAt time.Time,
+) (*time.Time, error) {
+	now := time.Now().UTC()
+	switch order.Status {
+	case "scheduled":
+		return order.ScheduledFor, nil
+	case "cooking":
+		const selectSQL = `
+			SELECT min(changed_at) FROM order_status_log WHERE order_id = $1 AND status = 'cooking';
+		`
+		var startedAt *time.Time
+		if err := q.QueryRow(ctx, selectSQL, order.ID).Scan(&startedAt); err != nil {
+			return nil, err
+		}
+		seconds, err := cookSeconds(ctx, q, order.Type, order.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if startedAt == nil {
+			startedAt = &now
+		}
+		eta := startedAt.Add(time.Duration(seconds * float64(time.Second))).UTC().Truncate(time.Second)
+		if eta.Before(now) {
+			eta = now.Truncate(time.Second)
+		}
+		return &eta, nil
+	case "received", "paid", "awaiting_payment":
+		load, err := kitchenLoad(ctx, q, order, createdAt)
+		if err != nil {
+			return nil, err
+		}
+		eta := services.EstimateReady(load, now)
+		return &eta, nil
+	}
+	return nil, nil
+}
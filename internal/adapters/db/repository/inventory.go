@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// INVENTORY
+func (r *Repository) GetInventory(ctx context.Context, locationID string) ([]domain.InventoryItem, error) {
+	const selectSQL = `
+		SELECT i.location_id, i.ingredient_code, g.name, g.unit, i.quantity, i.low_stock_threshold, i.updated_at
+		FROM inventory i
+		JOIN ingredients g ON g.code = i.ingredient_code
+		WHERE $1 = '' OR i.location_id = $1
+		ORDER BY i.location_id, i.ingredient_code;
+	`
+	rows, err := r.Conn.Query(ctx, selectSQL, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inventory := []domain.InventoryItem{}
+	for rows.Next() {
+		var item domain.InventoryItem
+		if err := rows.Scan(&item.LocationID, &item.IngredientCode, &item.Name, &item.Unit, &item.Quantity, &item.LowStockThreshold, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		item.LowStock = item.Quantity < item.LowStockThreshold
+		inventory = append(inventory, item)
+	}
+
+	return inventory, rows.Err()
+}
+
+func getRecipes(ctx context.Context, tx pgx.Tx, skus []string) (map[string][]domain.RecipeLine, error) {
+	const selectSQL = `
+		SELECT sku, ingredient_code, quantity
+		FROM recipes
+		WHERE sku = ANY($1);
+	`
+	rows, err := tx.Query(ctx, selectSQL, skus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipes := make(map[string][]domain.RecipeLine)
+	for rows.Next() {
+		var line domain.RecipeLine
+		if err := rows.Scan(&line.SKU, &line.IngredientCode, &line.Quantity); err != nil {
+			return nil, err
+		}
+		recipes[line.SKU] = append(recipes[line.SKU], line)
+	}
+
+	return recipes, rows.Err()
+}
+
+// reserveStock takes the ingredients of the order from the stock of its location. An order that already
+// holds a reservation (a modified order) only takes or gives back the difference, so every inventory row is
+// locked once, in ingredient order. The rows stay locked until the transaction ends, an order that needs
+// more than is in stock is rejected. Ingredients that fall below their threshold are announced on notifications_fanout.
+func reserveStock(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	recipes, err := getRecipes(ctx, tx, services.GetItemSKUs(order.Items))
+	if err != nil {
+		return err
+	}
+	needs := services.StockNeeds(*order, recipes)
+	reserved, err := getReservations(ctx, tx, order.ID)
+	if err != nil {
+		return err
+	}
+	if len(needs) == 0 && len(reserved) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(needs)+len(reserved))
+	for _, need := range needs {
+		codes = append(codes, need.IngredientCode)
+	}
+	for code := range reserved {
+		if !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	stock, err := lockStock(ctx, tx, order.LocationID, codes)
+	if err != nil {
+		return err
+	}
+
+	// The order's own reservation counts as available
+	needed := make(map[string]int64, len(needs))
+	var shortages domain.ValidationErrors
+	for _, need := range needs {
+		needed[need.IngredientCode] = need.Quantity
+		item := stock[need.IngredientCode]
+		available := item.Quantity + reserved[need.IngredientCode]
+		if available < need.Quantity {
+			sku := order.Items[need.ItemIndex].SKU
+			shortages = append(shortages, domain.FieldError{
+				Field:   fmt.Sprintf("items[%d].sku", need.ItemIndex),
+				Code:    domain.CodeOutOfStock,
+				Message: fmt.Sprintf("not enough %s for %s: %d %s needed, %d %s in stock", item.Name, sku, need.Quantity, item.Unit, available, item.Unit),
+			})
+		}
+	}
+	if len(shortages) > 0 {
+		return shortages
+	}
+
+	const updateSQL = `
+		UPDATE inventory
+		SET quantity = quantity - $3, updated_at = now()
+		WHERE location_id = $1 AND ingredient_code = $2;
+	`
+	for _, code := range codes {
+		change := needed[code] - reserved[code]
+		if change == 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, updateSQL, order.LocationID, code, change); err != nil {
+			return err
+		}
+
+		// Only the order that crosses the threshold sends the event
+		item := stock[code]
+		left := item.Quantity - change
+		if item.Quantity >= item.LowStockThreshold && left < item.LowStockThreshold {
+			msg := domain.LowStockMessage{
+				Event:             domain.LowStockEvent,
+				LocationID:        order.LocationID,
+				IngredientCode:    code,
+				Name:              item.Name,
+				Unit:              item.Unit,
+				Quantity:          left,
+				LowStockThreshold: item.LowStockThreshold,
+				OrderNumber:       order.Number,
+				TimeStamp:         time.Now().UTC(),
+			}
+			if err := insertLowStockOutbox(ctx, tx, order.ID, msg); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM stock_reservations WHERE order_id = $1`, order.ID); err != nil {
+		return err
+	}
+	const insertSQL = `
+		INSERT INTO stock_reservations (order_id, ingredient_code, location_id, quantity)
+		VALUES ($1, $2, $3, $4);
+	`
+	for _, need := range needs {
+		if _, err := tx.Exec(ctx, insertSQL, order.ID, need.IngredientCode, order.LocationID, need.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getReservations returns the reserved quantity per ingredient of the order
+func getReservations(ctx context.Context, tx pgx.Tx, orderID int) (map[string]int64, error) {
+	const selectSQL = `
+		SELECT ingredient_code, quantity FROM stock_reservations WHERE order_id = $1;
+	`
+	rows, err := tx.Query(ctx, selectSQL, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reserved := make(map[string]int64)
+	for rows.Next() {
+		var code string
+		var quantity int64
+		if err := rows.Scan(&code, &quantity); err != nil {
+			return nil, err
+		}
+		reserved[code] = quantity
+	}
+	return reserved, rows.Err()
+}
+
+// releaseStock gives the reserved ingredients of the order back to the stock
+func releaseStock(ctx context.Context, tx pgx.Tx, orderID int) error {
+	const selectSQL = `
+		SELECT location_id, ingredient_code, quantity
+		FROM stock_reservations
+		WHERE order_id = $1
+		ORDER BY ingredient_code;
+	`
+	rows, err := tx.Query(ctx, selectSQL, orderID)
+	if err != nil {
+		return err
+	}
+	type reservation struct {
+		locationID, ingredientCode string
+		quantity                   int64
+	}
+	var reservations []reservation
+	for rows.Next() {
+		var res reservation
+		if err := rows.Scan(&res.locationID, &res.ingredientCode, &res.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		reservations = append(reservations, res)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Rows are updated in ingredient order, the order reserveStock locks them in
+	const updateSQL = `
+		UPDATE inventory
+		SET quantity = quantity + $3, updated_at = now()
+		WHERE location_id = $1 AND ingredient_code = $2;
+	`
+	for _, res := range reservations {
+		if _, err := tx.Exec(ctx, updateSQL, res.locationID, res.ingredientCode, res.quantity); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM stock_reservations WHERE order_id = $1`, orderID)
+	return err
+}
+
+// lockBatchStock locks the inventory rows every order of a batch takes from, ordered by location and
+// ingredient. The orders of the batch then lock rows that are already held, so the batch cannot deadlock
+// with another batch or a single order that takes the same ingredients in a different sequence.
+func lockBatchStock(ctx context.Context, tx pgx.Tx, orders []*domain.Order) error {
+	var items []domain.OrderItem
+	for _, order := range orders {
+		items = append(items, order.Items...)
+	}
+	recipes, err := getRecipes(ctx, tx, services.GetItemSKUs(items))
+	if err != nil {
+		return err
+	}
+
+	var locations, codes []string
+	for _, order := range orders {
+		locationID := order.LocationID
+		if locationID == "" {
+			locationID = domain.DefaultLocation
+		}
+		for _, item := range order.Items {
+			for _, line := range recipes[item.SKU] {
+				locations = append(locations, locationID)
+				codes = append(codes, line.IngredientCode)
+			}
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	const lockSQL = `
+		SELECT 1
+		FROM inventory
+		WHERE (location_id, ingredient_code) IN (SELECT * FROM unnest($1::text[], $2::text[]))
+		ORDER BY location_id, ingredient_code
+		FOR UPDATE;
+	`
+	_, err = tx.Exec(ctx, lockSQL, locations, codes)
+	return err
+}
+
+// lockStock locks the inventory rows of the ingredients at the location in ingredient order.
+// Ingredients the location does not stock are returned with zero quantity.
+func lockStock(ctx context.Context, tx pgx.Tx, locationID string, codes []string) (map[string]domain.InventoryItem, error) {
+	const selectIngredientsSQL = `
+		SELECT code, name, unit FROM ingredients WHERE code = ANY($1);
+	`
+	rows, err := tx.Query(ctx, selectIngredientsSQL, codes)
+	if err != nil {
+		return nil, err
+	}
+	stock := make(map[string]domain.InventoryItem)
+	for rows.Next() {
+		item := domain.InventoryItem{LocationID: locationID}
+		if err := rows.Scan(&item.IngredientCode, &item.Name, &item.Unit); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stock[item.IngredientCode] = item
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const lockSQL = `
+		SELECT ingredient_code, quantity, low_stock_threshold
+		FROM inventory
+		WHERE location_id = $1 AND ingredient_code = ANY($2)
+		ORDER BY ingredient_code
+		FOR UPDATE;
+	`
+	rows, err = tx.Query(ctx, lockSQL, locationID, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		var quantity, threshold int64
+		if err := rows.Scan(&code, &quantity, &threshold); err != nil {
+			return nil, err
+		}
+		item := stock[code]
+		item.Quantity, item.LowStockThreshold = quantity, threshold
+		stock[code] = item
+	}
+
+	return stock, rows.Err()
+}
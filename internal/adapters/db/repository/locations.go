@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// checkLocation rejects orders for a restaurant that is not in the locations table
+func checkLocation(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, locationID string,
+) error {
+	var exists bool
+	if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM locations WHERE id = $1)`, locationID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ValidationErrors{{Field: "location_id", Code: domain.CodeUnknown, Message: fmt.Sprintf("unknown location %s", locationID)}}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MENU
+func (r *Repository) GetMenu(ctx context.Context) ([]domain.MenuItem, error) {
+	const q = `
+		SELECT sku, name, price, available, station, updated_at
+		FROM menu_items
+		ORDER BY sku;
+	`
+	rows, err := r.Conn.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	menu := []domain.MenuItem{}
+	for rows.Next() {
+		var item domain.MenuItem
+		if err := rows.Scan(&item.SKU, &item.Name, &item.Price, &item.Available, &item.Station, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		menu = append(menu, item)
+	}
+
+	return menu, rows.Err()
+}
+
+func (r *Repository) GetMenuItems(ctx context.Context, skus []string) (map[string]domain.MenuItem, error) {
+	return getMenuItems(ctx, r.Conn, skus, false)
+}
+
+// getMenuItems loads the catalog entries for skus, with lock=true the rows are share locked
+// so prices cannot change until the caller's transaction ends
+func getMenuItems(ctx context.Context, q interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}, skus []string, lock bool,
+) (map[string]domain.MenuItem, error) {
+	sql := `
+		SELECT sku, name, price, available, station, updated_at
+		FROM menu_items
+		WHERE sku = ANY($1)
+	`
+	if lock {
+		sql += " FOR SHARE"
+	}
+	rows, err := q.Query(ctx, sql, skus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	menu := make(map[string]domain.MenuItem)
+	for rows.Next() {
+		var item domain.MenuItem
+		if err := rows.Scan(&item.SKU, &item.Name, &item.Price, &item.Available, &item.Station, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		menu[item.SKU] = item
+	}
+
+	return menu, rows.Err()
+}
+
+func (r *Repository) ListMenuModifiers(ctx context.Context) ([]domain.MenuModifier, error) {
+	const q = `
+		SELECT code, name, kind, price_delta, available, updated_at
+		FROM menu_modifiers
+		ORDER BY kind, code;
+	`
+	rows, err := r.Conn.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modifiers := []domain.MenuModifier{}
+	for rows.Next() {
+		var modifier domain.MenuModifier
+		if err := rows.Scan(&modifier.Code, &modifier.Name, &modifier.Kind, &modifier.PriceDelta, &modifier.Available, &modifier.UpdatedAt); err != nil {
+			return nil, err
+		}
+		modifiers = append(modifiers, modifier)
+	}
+
+	return modifiers, rows.Err()
+}
+
+func (r *Repository) GetMenuModifiers(ctx context.Context, codes []string) (map[string]domain.MenuModifier, error) {
+	return getMenuModifiers(ctx, r.Conn, codes, false)
+}
+
+// getMenuModifiers loads the catalog entries for codes, lock works like in getMenuItems
+func getMenuModifiers(ctx context.Context, q interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}, codes []string, lock bool,
+) (map[string]domain.MenuModifier, error) {
+	modifiers := make(map[string]domain.MenuModifier)
+	if len(codes) == 0 {
+		return modifiers, nil
+	}
+
+	sql := `
+		SELECT code, name, kind, price_delta, available, updated_at
+		FROM menu_modifiers
+		WHERE code = ANY($1)
+	`
+	if lock {
+		sql += " FOR SHARE"
+	}
+	rows, err := q.Query(ctx, sql, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var modifier domain.MenuModifier
+		if err := rows.Scan(&modifier.Code, &modifier.Name, &modifier.Kind, &modifier.PriceDelta, &modifier.Available, &modifier.UpdatedAt); err != nil {
+			return nil, err
+		}
+		modifiers[modifier.Code] = modifier
+	}
+
+	return modifiers, rows.Err()
+}
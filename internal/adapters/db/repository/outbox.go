@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// insertOrderOutbox splits the order into station tickets and queues one message per ticket for orders_topic
+// inside the caller's transaction. Pending tickets of older versions of the order are superseded.
+func insertOrderOutbox(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	if err := supersedeStationTickets(ctx, tx, order.ID); err != nil {
+		return err
+	}
+
+	const insertSQL = `
+		INSERT INTO outbox (order_id, exchange, routing_key, payload, priority)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+	stations, items, err := services.SplitStations(*order)
+	if err != nil {
+		return err
+	}
+	for _, station := range stations {
+		ticketID, err := insertStationTicket(ctx, tx, order, station)
+		if err != nil {
+			return err
+		}
+
+		ticketOrder := *order
+		ticketOrder.Items = items[station]
+		payload, err := json.Marshal(domain.StationTicketMessage{TicketID: ticketID, Station: station, Order: ticketOrder})
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, insertSQL, order.ID, "orders_topic", services.StationRoutingKey(*order, station), payload, order.Priority); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertDeliveryOutbox queues a ready delivery order for the couriers inside the caller's transaction
+func insertDeliveryOutbox(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+
+	const insertSQL = `
+		INSERT INTO outbox (order_id, exchange, routing_key, payload, priority)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+	_, err = tx.Exec(ctx, insertSQL, order.ID, "orders_topic", services.DeliveryRoutingKey(*order), payload, order.Priority)
+	return err
+}
+
+// insertStatusUpdateOutbox queues a status update for notifications_fanout inside the caller's transaction
+func insertStatusUpdateOutbox(ctx context.Context, tx pgx.Tx, orderID int, msg domain.StatusUpdateMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	const insertSQL = `
+		INSERT INTO outbox (order_id, exchange, routing_key, payload)
+		VALUES ($1, $2, $3, $4);
+	`
+	_, err = tx.Exec(ctx, insertSQL, orderID, "notifications_fanout", "", payload)
+	return err
+}
+
+// insertLowStockOutbox queues a low-stock event for notifications_fanout inside the caller's transaction
+func insertLowStockOutbox(ctx context.Context, tx pgx.Tx, orderID int, msg domain.LowStockMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	const insertSQL = `
+		INSERT INTO outbox (order_id, exchange, routing_key, payload)
+		VALUES ($1, $2, $3, $4);
+	`
+	_, err = tx.Exec(ctx, insertSQL, orderID, "notifications_fanout", "", payload)
+	return err
+}
+
+// ClaimOutboxMessages leases up to limit due messages so that other relays skip them until the lease expires
+func (r *Repository) ClaimOutboxMessages(ctx context.Context, limit int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	const claimSQL = `
+		UPDATE outbox
+		SET next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, order_id, exchange, routing_key, payload, priority, attempts;
+	`
+	return r.claimOutboxMessages(ctx, claimSQL, limit, lease.Seconds())
+}
+
+// ClaimOrderOutboxMessages leases the due messages of one order, like ClaimOutboxMessages
+func (r *Repository) ClaimOrderOutboxMessages(ctx context.Context, orderID int, lease time.Duration) ([]domain.OutboxMessage, error) {
+	const claimSQL = `
+		UPDATE outbox
+		SET next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE order_id = $1 AND status = 'pending' AND next_attempt_at <= now()
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, order_id, exchange, routing_key, payload, priority, attempts;
+	`
+	return r.claimOutboxMessages(ctx, claimSQL, orderID, lease.Seconds())
+}
+
+func (r *Repository) claimOutboxMessages(ctx context.Context, claimSQL string, args ...any) ([]domain.OutboxMessage, error) {
+	rows, err := r.Conn.Query(ctx, claimSQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []domain.OutboxMessage
+	for rows.Next() {
+		var msg domain.OutboxMessage
+		if err := rows.Scan(&msg.ID, &msg.OrderID, &msg.Exchange, &msg.RoutingKey, &msg.Payload, &msg.Priority, &msg.Attempts); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Publish in insertion order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+func (r *Repository) MarkOutboxSent(ctx context.Context, id int) error {
+	const updateSQL = `
+		UPDATE outbox
+		SET status = 'sent', sent_at = now(), attempts = attempts + 1, last_error = NULL
+		WHERE id = $1;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, id)
+	return err
+}
+
+func (r *Repository) MarkOutboxFailed(ctx context.Context, id int, backoff time.Duration, publishErr error) error {
+	const updateSQL = `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			next_attempt_at = now() + make_interval(secs => $2),
+			last_error = $3
+		WHERE id = $1;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, id, backoff.Seconds(), publishErr.Error())
+	return err
+}
+
+// DelayOutboxMessages postpones messages that were claimed but not attempted
+func (r *Repository) DelayOutboxMessages(ctx context.Context, ids []int, delay time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	const updateSQL = `
+		UPDATE outbox
+		SET next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id = ANY($1);
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, ids, delay.Seconds())
+	return err
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StartCharge records a pending charge of the order before the provider is called, so that
+// no charge can be taken without a payments row
+func (r *Repository) StartCharge(ctx context.Context, order *domain.Order, payment *domain.Payment) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	payment.OrderID = order.ID
+	payment.Kind = "charge"
+	payment.Status = "pending"
+	if err := insertPayment(ctx, tx, payment); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RecordCharge saves the outcome of a charge started with StartCharge. A successful charge of an order awaiting payment moves it
+// to 'paid' and queues it for the kitchen in the same transaction; a scheduled order stays scheduled
+// and is dispatched when it is due. If the order was cancelled, paid or modified meanwhile, the charge is
+// saved with a pending refund and domain.ErrOrderNotPayable, ErrOrderAlreadyPaid or ErrOrderModified is returned.
+func (r *Repository) RecordCharge(ctx context.Context, order *domain.Order, payment *domain.Payment) error {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the order so that a cancellation or another payment waits
+	const selectSQL = `
+		SELECT status, version, total_amount FROM orders WHERE id = $1 FOR UPDATE;
+	`
+	var status string
+	var version int
+	var total domain.Money
+	if err := tx.QueryRow(ctx, selectSQL, order.ID).Scan(&status, &version, &total); err != nil {
+		return err
+	}
+
+	const updatePaymentSQL = `
+		UPDATE payments
+		SET status = $2, provider_ref = $3, error = $4, updated_at = now()
+		WHERE id = $1;
+	`
+	if _, err := tx.Exec(ctx, updatePaymentSQL, payment.ID, payment.Status, payment.ProviderRef, payment.Error); err != nil {
+		return err
+	}
+	if payment.Status != "succeeded" {
+		return tx.Commit(ctx)
+	}
+
+	paid, err := orderIsPaid(ctx, tx, order.ID, payment.ID)
+	if err != nil {
+		return err
+	}
+	var reason error
+	switch {
+	case paid:
+		reason = domain.ErrOrderAlreadyPaid
+	case status != "awaiting_payment" && status != "scheduled":
+		reason = domain.ErrOrderNotPayable
+	case version != order.Version || total.Amount != payment.Amount.Amount:
+		reason = domain.ErrOrderModified
+	}
+	if reason != nil {
+		// The money was taken anyway, it goes back to the customer
+		if err := insertRefunds(ctx, tx, order.ID, payment.ID); err != nil {
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		return reason
+	}
+
+	const insertStatusLogSQL = `
+		INSERT INTO order_status_log (order_id, status, changed_by, notes)
+		VALUES ($1, $2, $3, $4);
+	`
+	notes := fmt.Sprintf("Payment %s of %s captured", payment.Provider, payment.Amount)
+	if status == "scheduled" {
+		notes += ", the order is sent to the kitchen when it is due"
+		if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, status, "order-service", notes); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+
+	const updateSQL = `
+		UPDATE orders SET status = 'paid', updated_at = now() WHERE id = $1;
+	`
+	if _, err := tx.Exec(ctx, updateSQL, order.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, "paid", "order-service", notes); err != nil {
+		return err
+	}
+
+	// Paid orders go to the kitchen, the outbox relay publishes them after commit
+	order.Status = "paid"
+	if err := insertOrderOutbox(ctx, tx, order); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	msg := domain.StatusUpdateMessage{
+		OrderNumber:         order.Number,
+		OldStatus:           status,
+		NewStatus:           "paid",
+		ChangedBy:           "order-service",
+		TimeStamp:           now,
+		EstimatedCompletion: now,
+	}
+	if err := insertStatusUpdateOutbox(ctx, tx, order.ID, msg); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// OrderIsPaid reports whether the order has a successful charge
+func (r *Repository) OrderIsPaid(ctx context.Context, orderID int) (bool, error) {
+	return orderIsPaid(ctx, r.Conn, orderID, 0)
+}
+
+// orderIsPaid reports whether the order has a successful charge other than exceptID
+func orderIsPaid(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}, orderID, exceptID int,
+) (bool, error) {
+	const selectSQL = `
+		SELECT EXISTS (
+			SELECT 1 FROM payments
+			WHERE order_id = $1 AND kind = 'charge' AND status = 'succeeded' AND id <> $2
+		);
+	`
+	var paid bool
+	err := q.QueryRow(ctx, selectSQL, orderID, exceptID).Scan(&paid)
+	return paid, err
+}
+
+func insertPayment(ctx context.Context, tx pgx.Tx, payment *domain.Payment) error {
+	const insertSQL = `
+		INSERT INTO payments (order_id, kind, provider, provider_ref, refund_of, amount, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at;
+	`
+	return tx.QueryRow(ctx, insertSQL,
+		payment.OrderID,
+		payment.Kind,
+		payment.Provider,
+		payment.ProviderRef, // can be null
+		payment.RefundOf,    // can be null
+		payment.Amount,
+		payment.Status,
+		payment.Error, // can be null
+	).Scan(&payment.ID, &payment.CreatedAt)
+}
+
+// insertRefunds records a pending refund of every successful charge of the order that is not refunded yet,
+// or only of chargeID when it is not 0
+func insertRefunds(ctx context.Context, tx pgx.Tx, orderID, chargeID int) error {
+	const insertSQL = `
+		INSERT INTO payments (order_id, kind, provider, refund_of, amount, status)
+		SELECT order_id, 'refund', provider, id, amount, 'pending'
+		FROM payments c
+		WHERE order_id = $1 AND kind = 'charge' AND status = 'succeeded' AND ($2 = 0 OR id = $2)
+			AND NOT EXISTS (SELECT 1 FROM payments r WHERE r.refund_of = c.id);
+	`
+	_, err := tx.Exec(ctx, insertSQL, orderID, chargeID)
+	return err
+}
+
+// ClaimRefunds leases up to limit pending or failed refunds that were not touched for idle,
+// of one order or of all orders when orderNumber is empty
+func (r *Repository) ClaimRefunds(ctx context.Context, orderNumber string, idle time.Duration, limit int) ([]domain.PendingRefund, error) {
+	const claimSQL = `
+		WITH claimed AS (
+			UPDATE payments
+			SET updated_at = now()
+			WHERE id IN (
+				SELECT p.id FROM payments p
+				JOIN orders o ON o.id = p.order_id
+				WHERE p.kind = 'refund' AND p.status IN ('pending', 'failed')
+					AND p.updated_at <= now() - make_interval(secs => $2)
+					AND ($1 = '' OR o.number = $1)
+				ORDER BY p.id
+				LIMIT $3
+				FOR UPDATE OF p SKIP LOCKED
+			)
+			RETURNING id, created_at, order_id, kind, provider, provider_ref, refund_of, amount, status, error
+		)
+		SELECT r.id, r.created_at, r.order_id, r.kind, r.provider, r.provider_ref, r.refund_of, r.amount, r.status, r.error,
+			COALESCE(c.provider_ref, '')
+		FROM claimed r
+		JOIN payments c ON c.id = r.refund_of
+		ORDER BY r.id;
+	`
+	rows, err := r.Conn.Query(ctx, claimSQL, orderNumber, idle.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []domain.PendingRefund
+	for rows.Next() {
+		var pending domain.PendingRefund
+		refund := &pending.Refund
+		if err := rows.Scan(
+			&refund.ID, &refund.CreatedAt, &refund.OrderID, &refund.Kind, &refund.Provider, &refund.ProviderRef,
+			&refund.RefundOf, &refund.Amount, &refund.Status, &refund.Error, &pending.ChargeRef,
+		); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, pending)
+	}
+
+	return refunds, rows.Err()
+}
+
+// CompleteRefund records the provider's answer to a refund, failed refunds are retried later
+func (r *Repository) CompleteRefund(ctx context.Context, refund *domain.Payment, providerRef string, refundErr error) error {
+	refund.Status = "succeeded"
+	refund.Error = nil
+	if providerRef != "" {
+		refund.ProviderRef = &providerRef
+	}
+	if refundErr != nil {
+		msg := refundErr.Error()
+		refund.Status = "failed"
+		refund.Error = &msg
+	}
+
+	const updateSQL = `
+		UPDATE payments
+		SET status = $2, provider_ref = $3, error = $4, updated_at = now()
+		WHERE id = $1;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, refund.ID, refund.Status, refund.ProviderRef, refund.Error)
+	return err
+}
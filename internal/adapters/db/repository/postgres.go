@@ -15,13 +15,14 @@ import (
 )
 
 type Repository struct {
-	Conn       *pgxpool.Pool
-	DurationMs time.Duration
+	Conn           *pgxpool.Pool
+	DurationMs     time.Duration
+	priorityPolicy services.PriorityPolicy
 }
 
 var _ ports.RepositoryInterface = (*Repository)(nil)
 
-func NewRepository(cfg config.Config) (*Repository, error) {
+func NewRepository(cfg config.Config, priorityPolicy services.PriorityPolicy) (*Repository, error) {
 	start := time.Now()
 	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s",
 		cfg.Database.User, cfg.Database.Password, cfg.Database.Host,
@@ -39,11 +40,11 @@ func NewRepository(cfg config.Config) (*Repository, error) {
 	}
 
 	durationMs := time.Since(start).Milliseconds()
-	return &Repository{Conn: conn, DurationMs: time.Duration(durationMs)}, nil
+	return &Repository{Conn: conn, DurationMs: time.Duration(durationMs), priorityPolicy: priorityPolicy}, nil
 }
 
 // ORDERS
-func (r *Repository) InsertOrder(ctx context.Context, order *domain.Order) (string, error) {
+func (r *Repository) InsertOrder(ctx context.Context, order *domain.Order, idemKey *domain.IdempotencyKey) (string, error) {
 	// Get a pooled connection for transactional work
 	conn, err := r.Conn.Acquire(ctx)
 	if err != nil {
@@ -57,30 +58,156 @@ func (r *Repository) InsertOrder(ctx context.Context, order *domain.Order) (stri
 	}
 	defer tx.Rollback(ctx)
 
-	// Generate order number inside the transaction
-	order.Number, err = services.GenerateOrderNumber(ctx, tx)
-	if err != nil {
+	// Claim the idempotency key, concurrent retries wait here until the first one commits
+	if idemKey != nil {
+		replayed, err := claimIdempotencyKey(ctx, tx, idemKey)
+		if err != nil {
+			return "", err
+		}
+		if replayed {
+			return idemKey.Response.OrderNumber, nil
+		}
+	}
+
+	if err := r.insertOrderTx(ctx, tx, order); err != nil {
 		return "", err
 	}
 
-	// Calculating order's total price/amount
-	order.TotalAmount = 0
-	for _, item := range order.Items {
-		order.TotalAmount += float64(item.Quantity) * item.Price
+	// Store the response for later replays of the same key
+	if idemKey != nil {
+		response := services.NewPutOrderResponse(order)
+		idemKey.Response = &response
+		const updateKeySQL = `
+			UPDATE idempotency_keys
+			SET order_id = $1, response = $2
+			WHERE key = $3;
+		`
+		if _, err := tx.Exec(ctx, updateKeySQL, order.ID, idemKey.Response, idemKey.Key); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return order.Number, nil
+}
+
+// InsertOrders saves a batch of orders. With allOrNothing every order is saved in one transaction
+// and nothing is saved if one fails, otherwise each order gets its own transaction.
+// The returned slice holds the error of each order in input order.
+func (r *Repository) InsertOrders(ctx context.Context, orders []*domain.Order, allOrNothing bool) ([]error, error) {
+	errs := make([]error, len(orders))
+	if !allOrNothing {
+		for i, order := range orders {
+			_, errs[i] = r.InsertOrder(ctx, order, nil)
+		}
+		return errs, nil
+	}
+
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	// Calculating order's priority based on total_amount
-	order.Priority = services.AssignPriority(*order)
+	if err := lockBatchStock(ctx, tx, orders); err != nil {
+		return nil, err
+	}
+
+	for i, order := range orders {
+		if err := r.insertOrderTx(ctx, tx, order); err != nil {
+			errs[i] = err
+			return errs, domain.ErrBatchRolledBack
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// insertOrderTx saves the order with its items, status log and outbox message inside tx
+func (r *Repository) insertOrderTx(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	var err error
+
+	// Orders without a location belong to the default restaurant
+	if order.LocationID == "" {
+		order.LocationID = domain.DefaultLocation
+	}
+	if err := checkLocation(ctx, tx, order.LocationID); err != nil {
+		return err
+	}
+
+	// Dine-in tables must exist at the location and be open
+	if err := checkTable(ctx, tx, order); err != nil {
+		return err
+	}
+
+	// The order joins the tab of its table, scheduled ones when they are sent to the kitchen
+	if order.ScheduledFor == nil {
+		if err := joinTableSession(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+
+	// Orders are paid at the counter unless they are paid online
+	if order.PaymentMethod == "" {
+		order.PaymentMethod = domain.PaymentMethodCounter
+	}
+
+	// Generate order number of the location inside the transaction
+	order.Number, err = services.GenerateOrderNumber(ctx, tx, order.LocationID)
+	if err != nil {
+		return err
+	}
+
+	// Prices always come from the catalog
+	if err := applyMenuPrices(ctx, tx, order); err != nil {
+		return err
+	}
+
+	// Registered customer, created on the first order
+	if err := linkCustomer(ctx, tx, order); err != nil {
+		return err
+	}
+
+	// Discount is applied before the total and the priority are calculated
+	if err := applyPromotion(ctx, tx, order, time.Now()); err != nil {
+		return err
+	}
+
+	// Calculating order's total price/amount
+	order.TotalAmount = services.OrderTotal(*order)
+
+	// Calculating order's priority with the configured policy
+	order.Priority, order.PriorityReasons = r.priorityPolicy.Assign(*order, time.Now())
 
 	// Example insert into orders table
 	const insertOrderSQL = `
 		INSERT INTO orders (
 			number, customer_name, type, table_number, delivery_address,
-			total_amount, priority, status, processed_by, completed_at
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
-		RETURNING id;
+			total_amount, priority, status, processed_by, completed_at, priority_reasons, scheduled_for,
+			promo_code, discount_amount, customer_id, location_id, payment_method, table_session_id,
+			estimated_ready_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
+		RETURNING id, version;
 	`
 	order.Status = "received"
+	if order.PaymentMethod == domain.PaymentMethodOnline {
+		order.Status = "awaiting_payment"
+	}
+	if order.ScheduledFor != nil {
+		order.Status = "scheduled"
+	}
+
+	// The ETA depends on the kitchen queue, the online workers and the recent cook durations
+	order.EstimatedReadyAt, err = estimateReadyAt(ctx, tx, order, time.Now())
+	if err != nil {
+		return err
+	}
 	err = tx.QueryRow(ctx, insertOrderSQL,
 		order.Number,
 		order.CustomerName,
@@ -92,20 +219,32 @@ func (r *Repository) InsertOrder(ctx context.Context, order *domain.Order) (stri
 		order.Status,      // initial status
 		order.ProcessedBy, // can be null
 		order.CompletedAt, // can be null
-	).Scan(&order.ID)
+		order.PriorityReasons,
+		order.ScheduledFor, // can be null
+		order.PromoCode,    // can be null
+		order.DiscountAmount,
+		order.CustomerID, // can be null
+		order.LocationID,
+		order.PaymentMethod,
+		order.TableSessionID,   // can be null
+		order.EstimatedReadyAt, // can be null
+	).Scan(&order.ID, &order.Version)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	if err := redeemPromotion(ctx, tx, order); err != nil {
+		return err
 	}
 
 	// Insert order items
-	const insertItemSQL = `
-		INSERT INTO order_items (order_id, name, quantity, price)
-		VALUES ($1, $2, $3, $4);
-	`
-	for _, item := range order.Items {
-		if _, err := tx.Exec(ctx, insertItemSQL, order.ID, item.Name, item.Quantity, item.Price); err != nil {
-			return "", err
-		}
+	if err := insertOrderItems(ctx, tx, order); err != nil {
+		return err
+	}
+
+	// Ingredients are taken from the stock now, scheduled orders hold them until they are cooked
+	if err := reserveStock(ctx, tx, order); err != nil {
+		return err
 	}
 
 	// Insert into order_status_log
@@ -113,133 +252,473 @@ func (r *Repository) InsertOrder(ctx context.Context, order *domain.Order) (stri
 		INSERT INTO order_status_log (order_id, status, changed_by, notes)
 		VALUES ($1, $2, $3, $4);
 	`
-	if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, "received", order.ProcessedBy, "Order created"); err != nil {
-		return "", err
+	notes := "Order created"
+	if order.Status == "awaiting_payment" {
+		notes = "Order created, awaiting payment"
+	}
+	if order.ScheduledFor != nil {
+		notes = fmt.Sprintf("Order scheduled for %s", order.ScheduledFor.UTC().Format(time.RFC3339))
+	}
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, order.Status, order.ProcessedBy, notes); err != nil {
+		return err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		return "", err
+	// Scheduled orders are queued by the scheduler when they are due, online orders once they are paid
+	if order.Status != "received" {
+		return nil
 	}
 
-	return order.Number, nil
+	// Queue the kitchen message, the outbox relay publishes it after commit
+	if err := insertOrderOutbox(ctx, tx, order); err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func (r *Repository) OrderIsCooking(ctx context.Context, workerName string, order *domain.Order) error {
-	tx, err := r.Conn.BeginTx(ctx, pgx.TxOptions{})
+// applyMenuPrices resolves new items and their modifiers against the locked catalog rows
+func applyMenuPrices(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	menu, err := getMenuItems(ctx, tx, services.GetItemSKUs(order.Items), true)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback(ctx)
-	// Step 1: Update orders table
-	updateSQL := `
-		update orders
-		set status = 'cooking', processed_by = $1
-		where id = $2
-		`
-	_, err = tx.Exec(ctx, updateSQL, workerName, order.ID)
+	modifiers, err := getMenuModifiers(ctx, tx, services.GetModifierCodes(order.Items), true)
 	if err != nil {
 		return err
 	}
+	return services.ApplyMenuPrices(order, menu, modifiers)
+}
 
-	// Step 2: Insert into order_status_log
-	insertSQL := `
-		insert into order_status_log (order_id, status, changed_by)
-		values ($1, $2, $3)
-		`
-	_, err = tx.Exec(ctx, insertSQL, order.ID, "cooking", workerName)
+func insertOrderItems(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	const insertItemSQL = `
+		INSERT INTO order_items (order_id, sku, name, quantity, price, station)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id;
+	`
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+		if err := tx.QueryRow(ctx, insertItemSQL, order.ID, item.SKU, item.Name, item.Quantity, item.Price, item.Station).Scan(&item.ID); err != nil {
+			return err
+		}
+		if err := insertItemModifiers(ctx, tx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertItemModifiers(ctx context.Context, tx pgx.Tx, item *domain.OrderItem) error {
+	const insertModifierSQL = `
+		INSERT INTO order_item_modifiers (order_item_id, code, name, kind, price_delta)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;
+	`
+	for i := range item.Modifiers {
+		modifier := &item.Modifiers[i]
+		modifier.OrderItemID = item.ID
+		if err := tx.QueryRow(ctx, insertModifierSQL, item.ID, modifier.Code, modifier.Name, modifier.Kind, modifier.PriceDelta).Scan(&modifier.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimIdempotencyKey inserts the key or, if it already exists, loads the stored response.
+// It returns true when the stored response must be replayed instead of creating a new order.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, idemKey *domain.IdempotencyKey) (bool, error) {
+	const insertKeySQL = `
+		INSERT INTO idempotency_keys (key, request_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO NOTHING;
+	`
+	res, err := tx.Exec(ctx, insertKeySQL, idemKey.Key, idemKey.RequestHash)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if res.RowsAffected() == 1 {
+		return false, nil
 	}
 
-	// Commit both changes
-	if err := tx.Commit(ctx); err != nil {
-		return err
+	const selectKeySQL = `
+		SELECT request_hash, response
+		FROM idempotency_keys
+		WHERE key = $1;
+	`
+	var requestHash string
+	var response *domain.PutOrderResponse
+	if err := tx.QueryRow(ctx, selectKeySQL, idemKey.Key).Scan(&requestHash, &response); err != nil {
+		return false, err
+	}
+	if requestHash != idemKey.RequestHash {
+		return false, domain.ErrIdempotencyKeyReused
+	}
+	if response == nil {
+		return false, fmt.Errorf("idempotency key %s has no stored response", idemKey.Key)
 	}
 
-	order.ProcessedBy = &workerName
-	order.Status = "cooking"
-	return nil
+	idemKey.Response = response
+	idemKey.Replayed = true
+	return true, nil
 }
 
-func (r *Repository) GetOrderStatus(ctx context.Context, orderID int) (string, error) {
-	const selectSQL = `
-		SELECT status FROM orders WHERE id = $1;
+func (r *Repository) GetOrder(ctx context.Context, orderNumber string) (domain.Order, error) {
+	return getOrder(ctx, r.Conn, orderNumber)
+}
+
+// getOrder loads the order with its items, q is the pool or a transaction
+func getOrder(ctx context.Context, q interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}, orderNumber string,
+) (domain.Order, error) {
+	const selectOrderSQL = `
+		SELECT id, created_at, updated_at, number, customer_name, type, table_number, delivery_address,
+			total_amount, priority, COALESCE(priority_reasons, '{}'), status, processed_by, completed_at, version, scheduled_for,
+			promo_code, discount_amount, customer_id, location_id, payment_method, table_session_id, estimated_ready_at
+		FROM orders
+		WHERE number = $1;
 	`
-	var status string
-	err := r.Conn.QueryRow(ctx, selectSQL, orderID).Scan(&status)
+	var order domain.Order
+	err := q.QueryRow(ctx, selectOrderSQL, orderNumber).Scan(
+		&order.ID, &order.CreatedAt, &order.UpdatedAt, &order.Number, &order.CustomerName, &order.Type,
+		&order.TableNumber, &order.DeliveryAddress, &order.TotalAmount, &order.Priority, &order.PriorityReasons, &order.Status,
+		&order.ProcessedBy, &order.CompletedAt, &order.Version, &order.ScheduledFor,
+		&order.PromoCode, &order.DiscountAmount, &order.CustomerID, &order.LocationID, &order.PaymentMethod,
+		&order.TableSessionID, &order.EstimatedReadyAt,
+	)
 	if err != nil {
-		return status, err
+		return order, err
 	}
 
-	return status, nil
+	const selectItemsSQL = `
+		SELECT id, created_at, order_id, COALESCE(sku, ''), name, quantity, price, station
+		FROM order_items
+		WHERE order_id = $1
+		ORDER BY id;
+	`
+	rows, err := q.Query(ctx, selectItemsSQL, order.ID)
+	if err != nil {
+		return order, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item domain.OrderItem
+		if err := rows.Scan(&item.ID, &item.CreatedAt, &item.OrderID, &item.SKU, &item.Name, &item.Quantity, &item.Price, &item.Station); err != nil {
+			return order, err
+		}
+		item.Stored = true
+		order.Items = append(order.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return order, err
+	}
+
+	const selectModifiersSQL = `
+		SELECT m.id, m.order_item_id, m.code, m.name, m.kind, m.price_delta
+		FROM order_item_modifiers m
+		JOIN order_items i ON i.id = m.order_item_id
+		WHERE i.order_id = $1
+		ORDER BY m.id;
+	`
+	modifierRows, err := q.Query(ctx, selectModifiersSQL, order.ID)
+	if err != nil {
+		return order, err
+	}
+	defer modifierRows.Close()
+
+	itemIndex := make(map[int]int, len(order.Items))
+	for i, item := range order.Items {
+		itemIndex[item.ID] = i
+	}
+	for modifierRows.Next() {
+		var modifier domain.OrderItemModifier
+		if err := modifierRows.Scan(&modifier.ID, &modifier.OrderItemID, &modifier.Code, &modifier.Name, &modifier.Kind, &modifier.PriceDelta); err != nil {
+			return order, err
+		}
+		if i, ok := itemIndex[modifier.OrderItemID]; ok {
+			order.Items[i].Modifiers = append(order.Items[i].Modifiers, modifier)
+		}
+	}
+
+	return order, modifierRows.Err()
 }
 
-func (r *Repository) OrderIsReady(ctx context.Context, workerName string, order *domain.Order) error {
+// UpdateOrderItems replaces the items of a received or unpaid order, recalculates its total and priority
+// and queues the new version for the kitchen. The order must not have changed since it was read.
+func (r *Repository) UpdateOrderItems(ctx context.Context, order *domain.Order, diff string) error {
 	tx, err := r.Conn.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	// Update order status → ready
-	updateOrderSQL := `
-        update orders
-        set status = 'ready',
-            completed_at = now()
-        where id = $1
-    `
-	res, err := tx.Exec(ctx, updateOrderSQL, order.ID)
-	if err != nil {
+	oldTotal := order.TotalAmount
+
+	// Prices of the added items come from the catalog
+	if err := applyMenuPrices(ctx, tx, order); err != nil {
 		return err
 	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("order %d not found", order.ID)
+
+	// The redeemed promotion is applied to the new items
+	if err := recalculatePromotion(ctx, tx, order, time.Now()); err != nil {
+		return err
 	}
 
-	// Increment worker’s orders_processed count
-	updateWorkerSQL := `
-        update workers
-        set orders_processed = orders_processed + 1,
-            last_seen = now()
-        where name = $1
-    `
-	res, err = tx.Exec(ctx, updateWorkerSQL, workerName)
+	// Calculating order's total price/amount
+	order.TotalAmount = services.OrderTotal(*order)
+
+	// Calculating order's priority with the configured policy
+	order.Priority, order.PriorityReasons = r.priorityPolicy.Assign(*order, time.Now())
+
+	// The new priority moves the order in the queue
+	order.EstimatedReadyAt, err = estimateReadyAt(ctx, tx, order, order.CreatedAt)
 	if err != nil {
 		return err
 	}
-	if res.RowsAffected() == 0 {
-		return fmt.Errorf("worker %s not found", workerName)
+
+	const updateOrderSQL = `
+		UPDATE orders
+		SET total_amount = $1, priority = $2, priority_reasons = $3, discount_amount = $4,
+			estimated_ready_at = $7, version = version + 1, updated_at = now()
+		WHERE id = $5 AND status IN ('received', 'scheduled', 'awaiting_payment') AND version = $6
+			AND NOT EXISTS (SELECT 1 FROM payments WHERE order_id = $5 AND kind = 'charge' AND status = 'succeeded')
+		RETURNING version, updated_at;
+	`
+	err = tx.QueryRow(ctx, updateOrderSQL, order.TotalAmount, order.Priority, order.PriorityReasons, order.DiscountAmount, order.ID, order.Version, order.EstimatedReadyAt).Scan(&order.Version, &order.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		var status string
+		if err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1`, order.ID).Scan(&status); err != nil {
+			return err
+		}
+		if status != "received" && status != "scheduled" && status != "awaiting_payment" {
+			return domain.ErrOrderNotModifiable
+		}
+		// Scheduled online orders can be paid in advance, the paid total cannot change anymore
+		paid, err := orderIsPaid(ctx, tx, order.ID, 0)
+		if err != nil {
+			return err
+		}
+		if paid {
+			return domain.ErrOrderAlreadyPaid
+		}
+		return domain.ErrOrderModified
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM order_items WHERE order_id = $1`, order.ID); err != nil {
+		return err
 	}
 
-	// Insert into order_status_log
-	insertSQL := `
-		insert into order_status_log (order_id, status, changed_by)
-		values ($1, $2, $3)
+	if err := insertOrderItems(ctx, tx, order); err != nil {
+		return err
+	}
+
+	// Only the difference to the stock the old items hold is taken or given back
+	if err := reserveStock(ctx, tx, order); err != nil {
+		return err
+	}
+
+	const insertStatusLogSQL = `
+		INSERT INTO order_status_log (order_id, status, changed_by, notes)
+		VALUES ($1, $2, $3, $4);
 	`
-	_, err = tx.Exec(ctx, insertSQL, order.ID, "ready", workerName)
-	if err != nil {
+	notes := fmt.Sprintf("Order modified: %s; total %s -> %s", diff, oldTotal, order.TotalAmount)
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, order.Status, "order-service", notes); err != nil {
 		return err
 	}
-	// Commit transaction
+
+	// The kitchen drops older versions of the order message, scheduled orders are not queued yet
+	if order.Status == "received" {
+		if err := insertOrderOutbox(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DispatchScheduledOrders moves scheduled orders whose kitchen start time has come to 'received'
+// and queues them for the kitchen, online orders only once they are paid. Rows are locked with SKIP LOCKED so several order-services
+// can run the scheduler, and the state lives in the database so restarts lose nothing.
+func (r *Repository) DispatchScheduledOrders(ctx context.Context, limit int) ([]string, error) {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Candidates are due within the longest cooking time, the exact start time depends on the order type
+	maxCookingTime := 0
+	for _, orderType := range []string{"dine_in", "takeout", "delivery"} {
+		maxCookingTime = max(maxCookingTime, services.CookingTimeSeconds(orderType))
+	}
+	const selectSQL = `
+		SELECT number FROM orders
+		WHERE status = 'scheduled' AND scheduled_for <= now() + make_interval(secs => $1)
+			AND (payment_method = 'counter' OR EXISTS (
+				SELECT 1 FROM payments WHERE order_id = orders.id AND kind = 'charge' AND status = 'succeeded'
+			))
+		ORDER BY scheduled_for
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED;
+	`
+	rows, err := tx.Query(ctx, selectSQL, maxCookingTime, limit)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	var dispatched []string
+	now := time.Now()
+	for _, number := range numbers {
+		order, err := getOrder(ctx, tx, number)
+		if err != nil {
+			return nil, err
+		}
+		cookingTime := time.Duration(services.CookingTimeSeconds(order.Type)) * time.Second
+		if order.ScheduledFor.Add(-cookingTime).After(now) {
+			continue
+		}
+
+		// Dine-in orders join the tab of their table now
+		if err := joinTableSession(ctx, tx, &order); err != nil {
+			return nil, err
+		}
+
+		order.Status = "received"
+		const updateSQL = `
+			UPDATE orders SET status = 'received', table_session_id = $2, updated_at = now() WHERE id = $1;
+		`
+		if _, err := tx.Exec(ctx, updateSQL, order.ID, order.TableSessionID); err != nil {
+			return nil, err
+		}
+		const insertStatusLogSQL = `
+			INSERT INTO order_status_log (order_id, status, changed_by, notes)
+			VALUES ($1, $2, $3, $4);
+		`
+		if _, err := tx.Exec(ctx, insertStatusLogSQL, order.ID, "received", "scheduler", "Scheduled order sent to the kitchen"); err != nil {
+			return nil, err
+		}
+		if err := insertOrderOutbox(ctx, tx, &order); err != nil {
+			return nil, err
+		}
+		dispatched = append(dispatched, order.Number)
+	}
+
 	if err := tx.Commit(ctx); err != nil {
-		return err
+		return nil, err
+	}
+	return dispatched, nil
+}
+
+// CancelOrder moves the order to 'cancelled' and queues the status update in the same transaction.
+// Orders that are already cooking can only be cancelled with managerOverride.
+// A pending refund is recorded for every successful charge, the caller sends them with SendRefunds.
+func (r *Repository) CancelOrder(ctx context.Context, orderNumber, reason string, managerOverride bool) (string, error) {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return "", err
 	}
-	t := time.Now()
-	order.CompletedAt = &t
-	order.ProcessedBy = &workerName
-	order.Status = "ready"
+	defer tx.Rollback(ctx)
 
-	return nil
+	// Lock the order so the kitchen cannot pick it up meanwhile
+	const selectSQL = `
+		SELECT id, status FROM orders WHERE number = $1 FOR UPDATE;
+	`
+	var orderID int
+	var oldStatus string
+	if err := tx.QueryRow(ctx, selectSQL, orderNumber).Scan(&orderID, &oldStatus); err != nil {
+		return "", err
+	}
+
+	switch oldStatus {
+	case "received", "scheduled", "awaiting_payment", "paid":
+	case "cooking":
+		if !managerOverride {
+			return oldStatus, domain.ErrOrderCookingNoManager
+		}
+	default:
+		return oldStatus, domain.ErrOrderNotCancellable
+	}
+
+	const updateSQL = `
+		UPDATE orders
+		SET status = 'cancelled', updated_at = now()
+		WHERE id = $1;
+	`
+	if _, err := tx.Exec(ctx, updateSQL, orderID); err != nil {
+		return "", err
+	}
+
+	const insertStatusLogSQL = `
+		INSERT INTO order_status_log (order_id, status, changed_by, notes)
+		VALUES ($1, $2, $3, $4);
+	`
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, orderID, "cancelled", "order-service", reason); err != nil {
+		return "", err
+	}
+
+	if err := insertRefunds(ctx, tx, orderID, 0); err != nil {
+		return "", err
+	}
+
+	// Ingredients of an order that is cooking are already used
+	if oldStatus != "cooking" {
+		if err := releaseStock(ctx, tx, orderID); err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now().UTC()
+	msg := domain.StatusUpdateMessage{
+		OrderNumber:         orderNumber,
+		OldStatus:           oldStatus,
+		NewStatus:           "cancelled",
+		ChangedBy:           "order-service",
+		TimeStamp:           now,
+		EstimatedCompletion: now,
+	}
+	if err := insertStatusUpdateOutbox(ctx, tx, orderID, msg); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return oldStatus, nil
+}
+
+func (r *Repository) GetOrderStatus(ctx context.Context, orderID int) (string, error) {
+	const selectSQL = `
+		SELECT status FROM orders WHERE id = $1;
+	`
+	var status string
+	err := r.Conn.QueryRow(ctx, selectSQL, orderID).Scan(&status)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
 }
 
 // KITCHEN WORKERS
-func (r *Repository) InsertWorker(ctx context.Context, workerName string, orderTypes []string) error {
+func (r *Repository) InsertWorker(ctx context.Context, workerName string, orderTypes, stations []string, locationID string) error {
+	if err := checkLocation(ctx, r.Conn, locationID); err != nil {
+		return err
+	}
 	const insertSQL = `
-		INSERT INTO workers (name, type, status, last_seen)
-		VALUES ($1, $2, 'online', $3);
+		INSERT INTO workers (name, type, status, last_seen, location_id, stations)
+		VALUES ($1, $2, 'online', $3, $4, $5);
 	`
 	orderTypesStr := strings.Join(orderTypes, ",")
-	_, err := r.Conn.Exec(ctx, insertSQL, workerName, orderTypesStr, time.Now().UTC())
+	_, err := r.Conn.Exec(ctx, insertSQL, workerName, orderTypesStr, time.Now().UTC(), locationID, strings.Join(stations, ","))
 	return err
 }
 
@@ -253,6 +732,30 @@ func (r *Repository) UpdateWorkerStatus(ctx context.Context, workerName, status
 	return err
 }
 
+func (r *Repository) UpdateWorkerLocation(ctx context.Context, workerName, locationID string) error {
+	if err := checkLocation(ctx, r.Conn, locationID); err != nil {
+		return err
+	}
+	const updateSQL = `
+		UPDATE workers
+		SET location_id = $1
+		WHERE name = $2;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, locationID, workerName)
+	return err
+}
+
+// UpdateWorkerStations records the stations a returning worker cooks at now
+func (r *Repository) UpdateWorkerStations(ctx context.Context, workerName string, stations []string) error {
+	const updateSQL = `
+		UPDATE workers
+		SET stations = $1
+		WHERE name = $2;
+	`
+	_, err := r.Conn.Exec(ctx, updateSQL, strings.Join(stations, ","), workerName)
+	return err
+}
+
 func (r *Repository) GetWorkerStatus(ctx context.Context, workerName string) (string, error) {
 	const selectSQL = `
 		SELECT status FROM workers WHERE name = $1;
@@ -279,14 +782,29 @@ func (r *Repository) UpdateWorkerHeartbeat(ctx context.Context, workerName strin
 
 // TRACKING SERVICE
 
-func (r *Repository) GetOrderDetails(ctx context.Context, orderNumber string) (domain.OrderDetailsResponse, error) {
+// GetOrderDetails returns the status of the order, an empty locationID matches every location. The ETA of an
+// order that is not ready yet is estimated again from the current kitchen load, ready orders report their completion time.
+func (r *Repository) GetOrderDetails(ctx context.Context, orderNumber, locationID string) (domain.OrderDetailsResponse, error) {
 	const q = `
-		SELECT number, status, completed_at, processed_by, updated_at
+		SELECT id, created_at, number, status, type, priority, completed_at, processed_by, updated_at, scheduled_for, location_id
 		FROM orders
-		WHERE number = $1
+		WHERE number = $1 AND ($2 = '' OR location_id = $2)
 	`
+	var order domain.Order
 	orderDetails := domain.OrderDetailsResponse{}
-	err := r.Conn.QueryRow(ctx, q, orderNumber).Scan(&orderDetails.OrderNumber, &orderDetails.CurrentStatus, &orderDetails.EstimatedCompletion, &orderDetails.ProcessedBy, &orderDetails.UpdatedAt)
+	err := r.Conn.QueryRow(ctx, q, orderNumber, locationID).Scan(&order.ID, &order.CreatedAt, &order.Number, &order.Status, &order.Type, &order.Priority, &order.CompletedAt, &orderDetails.ProcessedBy, &orderDetails.UpdatedAt, &order.ScheduledFor, &order.LocationID)
+	if err != nil {
+		return orderDetails, err
+	}
+	orderDetails.OrderNumber = order.Number
+	orderDetails.CurrentStatus = order.Status
+	orderDetails.ScheduledFor = order.ScheduledFor
+	orderDetails.LocationID = order.LocationID
+
+	orderDetails.EstimatedCompletion = order.CompletedAt
+	if order.CompletedAt == nil {
+		orderDetails.EstimatedCompletion, err = estimateReadyAt(ctx, r.Conn, &order, order.CreatedAt)
+	}
 
 	return orderDetails, err
 }
@@ -325,12 +843,14 @@ func (r *Repository) GetOrderHistory(ctx context.Context, orderNumber string) ([
 	return history, err
 }
 
-func (r *Repository) GetWorkersStatuses(ctx context.Context, heartbeatTimeout time.Duration) ([]map[string]interface{}, error) {
+// GetWorkersStatuses lists the workers, an empty locationID matches every location
+func (r *Repository) GetWorkersStatuses(ctx context.Context, heartbeatTimeout time.Duration, locationID string) ([]map[string]interface{}, error) {
 	const q = `
-		SELECT name, status, orders_processed, last_seen
+		SELECT name, status, orders_processed, last_seen, location_id, stations
 		FROM workers
+		WHERE $1 = '' OR location_id = $1
 	`
-	rows, err := r.Conn.Query(ctx, q)
+	rows, err := r.Conn.Query(ctx, q, locationID)
 	if err != nil {
 		return nil, err
 	}
@@ -340,10 +860,10 @@ func (r *Repository) GetWorkersStatuses(ctx context.Context, heartbeatTimeout ti
 	now := time.Now().UTC()
 
 	for rows.Next() {
-		var name, status string
+		var name, status, location, stations string
 		var ordersProcessed int
 		var lastSeen time.Time
-		if err := rows.Scan(&name, &status, &ordersProcessed, &lastSeen); err != nil {
+		if err := rows.Scan(&name, &status, &ordersProcessed, &lastSeen, &location, &stations); err != nil {
 			return nil, err
 		}
 
@@ -357,6 +877,8 @@ func (r *Repository) GetWorkersStatuses(ctx context.Context, heartbeatTimeout ti
 			"status":           status,
 			"orders_processed": ordersProcessed,
 			"last_seen":        lastSeen.UTC(),
+			"location_id":      location,
+			"stations":         strings.Split(stations, ","),
 		})
 	}
 
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PROMOTIONS
+func getPromotion(ctx context.Context, tx pgx.Tx, code string) (domain.Promotion, error) {
+	// The row lock keeps the usage limit exact under concurrent orders
+	const selectSQL = `
+		SELECT code, description, rule_type, percent, amount, sku_prefix, bundle_quantity,
+			order_type, weekday, min_total, valid_from, valid_until, max_uses, uses, active
+		FROM promotions
+		WHERE code = $1
+		FOR UPDATE;
+	`
+	var promo domain.Promotion
+	err := tx.QueryRow(ctx, selectSQL, code).Scan(
+		&promo.Code, &promo.Description, &promo.RuleType, &promo.Percent, &promo.Amount, &promo.SKUPrefix,
+		&promo.BundleQuantity, &promo.OrderType, &promo.Weekday, &promo.MinTotal, &promo.ValidFrom,
+		&promo.ValidUntil, &promo.MaxUses, &promo.Uses, &promo.Active,
+	)
+	if err == pgx.ErrNoRows {
+		return promo, domain.ValidationErrors{{Field: "promo_code", Code: domain.CodeUnknown, Message: fmt.Sprintf("unknown promotion %s", code)}}
+	}
+	return promo, err
+}
+
+// applyPromotion validates the promo code of a new order and sets its discount
+func applyPromotion(ctx context.Context, tx pgx.Tx, order *domain.Order, at time.Time) error {
+	order.DiscountAmount = domain.NewMoney(0)
+	if order.PromoCode == nil {
+		return nil
+	}
+	code := strings.ToUpper(strings.TrimSpace(*order.PromoCode))
+	if code == "" {
+		order.PromoCode = nil
+		return nil
+	}
+	order.PromoCode = &code
+
+	promo, err := getPromotion(ctx, tx, code)
+	if err != nil {
+		return err
+	}
+	if err := services.CheckPromotion(promo, *order, at); err != nil {
+		return err
+	}
+	order.DiscountAmount = services.CalculateDiscount(*order, promo)
+	return nil
+}
+
+// redeemPromotion records the discount of a saved order and counts the use
+func redeemPromotion(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	if order.PromoCode == nil {
+		return nil
+	}
+	const insertSQL = `
+		INSERT INTO promotion_redemptions (promo_code, order_id, discount_amount)
+		VALUES ($1, $2, $3);
+	`
+	if _, err := tx.Exec(ctx, insertSQL, *order.PromoCode, order.ID, order.DiscountAmount); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `UPDATE promotions SET uses = uses + 1 WHERE code = $1`, *order.PromoCode)
+	return err
+}
+
+// recalculatePromotion checks the redeemed promotion again and updates its discount after the items changed.
+// A modified order that does not qualify anymore (below min_total, promotion expired) is rejected.
+func recalculatePromotion(ctx context.Context, tx pgx.Tx, order *domain.Order, at time.Time) error {
+	order.DiscountAmount = domain.NewMoney(0)
+	if order.PromoCode == nil {
+		return nil
+	}
+	promo, err := getPromotion(ctx, tx, *order.PromoCode)
+	if err != nil {
+		return err
+	}
+	// The order's own redemption is already counted in uses
+	promo.Uses--
+	if err := services.CheckPromotion(promo, *order, at); err != nil {
+		return err
+	}
+	order.DiscountAmount = services.CalculateDiscount(*order, promo)
+
+	const updateSQL = `
+		UPDATE promotion_redemptions SET discount_amount = $1 WHERE order_id = $2;
+	`
+	_, err = tx.Exec(ctx, updateSQL, order.DiscountAmount, order.ID)
+	return err
+}
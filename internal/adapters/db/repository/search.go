@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// SearchOrders returns up to filter.Limit+1 orders matching the filter, ordered by the sort column
+// and id. The extra row tells the caller that there is a next page.
+func (r *Repository) SearchOrders(ctx context.Context, filter domain.OrderSearchFilter) ([]domain.OrderSummary, error) {
+	var conditions []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+arg(filter.Statuses)+")")
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = "+arg(filter.Type))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.To))
+	}
+	if filter.CustomerID != nil {
+		conditions = append(conditions, "customer_id = "+arg(*filter.CustomerID))
+	}
+	if filter.CustomerName != "" {
+		// Prefix match that can use orders_customer_name_idx, LIKE wildcards in the input are literal
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(filter.CustomerName))
+		conditions = append(conditions, "lower(customer_name) LIKE "+arg(escaped+"%"))
+	}
+	if filter.Worker != "" {
+		conditions = append(conditions, "processed_by = "+arg(filter.Worker))
+	}
+	if filter.LocationID != "" {
+		conditions = append(conditions, "location_id = "+arg(filter.LocationID))
+	}
+
+	// Keyset pagination: continue after the (sort column, id) of the cursor
+	sortColumn := "created_at"
+	if filter.SortBy == "priority" {
+		sortColumn = "priority"
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		var value any = filter.After.CreatedAt
+		if sortColumn == "priority" {
+			value = filter.After.Priority
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", sortColumn, comparison, arg(value), arg(filter.After.ID)))
+	}
+
+	q := `
+		SELECT id, number, customer_name, customer_id, type, status, priority, total_amount,
+			processed_by, location_id, created_at, updated_at, completed_at, scheduled_for
+		FROM orders
+	`
+	if len(conditions) > 0 {
+		q += "WHERE " + strings.Join(conditions, " AND ") + "\n"
+	}
+	q += fmt.Sprintf("ORDER BY %s %s, id %s LIMIT %s;", sortColumn, direction, direction, arg(filter.Limit+1))
+
+	rows, err := r.Conn.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.OrderSummary{}
+	for rows.Next() {
+		var order domain.OrderSummary
+		if err := rows.Scan(
+			&order.ID, &order.OrderNumber, &order.CustomerName, &order.CustomerID, &order.Type, &order.Status,
+			&order.Priority, &order.TotalAmount, &order.ProcessedBy, &order.LocationID, &order.CreatedAt,
+			&order.UpdatedAt, &order.CompletedAt, &order.ScheduledFor,
+		); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
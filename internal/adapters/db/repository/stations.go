@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// supersedeStationTickets drops the pending tickets of older versions of a modified order
+func supersedeStationTickets(ctx context.Context, tx pgx.Tx, orderID int) error {
+	const updateSQL = `
+		UPDATE order_station_tickets
+		SET status = 'superseded', updated_at = now()
+		WHERE order_id = $1 AND status = 'pending';
+	`
+	_, err := tx.Exec(ctx, updateSQL, orderID)
+	return err
+}
+
+func insertStationTicket(ctx context.Context, tx pgx.Tx, order *domain.Order, station string) (int, error) {
+	const insertSQL = `
+		INSERT INTO order_station_tickets (order_id, order_version, station)
+		VALUES ($1, $2, $3)
+		RETURNING id;
+	`
+	var ticketID int
+	err := tx.QueryRow(ctx, insertSQL, order.ID, order.Version, station).Scan(&ticketID)
+	return ticketID, err
+}
+
+// lockTicket locks the order and the ticket and rejects tickets that must not be cooked anymore.
+// It returns the status of the order and of the ticket.
+func lockTicket(ctx context.Context, tx pgx.Tx, ticket *domain.StationTicketMessage) (string, string, error) {
+	var orderStatus, ticketStatus string
+	if err := tx.QueryRow(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, ticket.Order.ID).Scan(&orderStatus); err == pgx.ErrNoRows {
+		return "", "", fmt.Errorf("order %d not found", ticket.Order.ID)
+	} else if err != nil {
+		return "", "", err
+	}
+	if err := tx.QueryRow(ctx, `SELECT status FROM order_station_tickets WHERE id = $1 FOR UPDATE`, ticket.TicketID).Scan(&ticketStatus); err == pgx.ErrNoRows {
+		return "", "", fmt.Errorf("station ticket %d not found", ticket.TicketID)
+	} else if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case orderStatus == "cancelled":
+		return "", "", domain.ErrOrderCancelled
+	case ticketStatus == "superseded":
+		return "", "", domain.ErrStaleOrderMessage
+	case ticketStatus == "done":
+		return "", "", domain.ErrTicketDone
+	}
+	return orderStatus, ticketStatus, nil
+}
+
+// TicketIsCooking starts a station ticket. The first started ticket moves the order to 'cooking',
+// the previous status of the order is returned. A redelivered ticket that is already cooking is accepted.
+func (r *Repository) TicketIsCooking(ctx context.Context, workerName string, ticket *domain.StationTicketMessage) (string, error) {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	orderStatus, ticketStatus, err := lockTicket(ctx, tx, ticket)
+	if err != nil {
+		return "", err
+	}
+	if orderStatus != "received" && orderStatus != "paid" && orderStatus != "cooking" {
+		return "", fmt.Errorf("order %d has unexpected status %s", ticket.Order.ID, orderStatus)
+	}
+
+	const updateTicketSQL = `
+		UPDATE order_station_tickets
+		SET status = 'cooking', worker = $2, started_at = COALESCE(started_at, now()), updated_at = now()
+		WHERE id = $1;
+	`
+	if _, err := tx.Exec(ctx, updateTicketSQL, ticket.TicketID, workerName); err != nil {
+		return "", err
+	}
+
+	if orderStatus != "cooking" {
+		const updateOrderSQL = `
+			UPDATE orders
+			SET status = 'cooking', processed_by = $2, updated_at = now()
+			WHERE id = $1;
+		`
+		if _, err := tx.Exec(ctx, updateOrderSQL, ticket.Order.ID, workerName); err != nil {
+			return "", err
+		}
+	}
+
+	if ticketStatus == "pending" {
+		const insertStatusLogSQL = `
+			INSERT INTO order_status_log (order_id, status, changed_by, notes)
+			VALUES ($1, $2, $3, $4);
+		`
+		notes := fmt.Sprintf("Station %s started", ticket.Station)
+		if _, err := tx.Exec(ctx, insertStatusLogSQL, ticket.Order.ID, "cooking", workerName, notes); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	ticket.Order.Status = "cooking"
+	return orderStatus, nil
+}
+
+// TicketIsDone finishes a station ticket. When every ticket of the order is done the order moves to 'ready'
+// (ready delivery orders are queued for the couriers) and true is returned.
+func (r *Repository) TicketIsDone(ctx context.Context, workerName string, ticket *domain.StationTicketMessage) (bool, error) {
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The order lock serializes the last tickets of an order, exactly one of them makes it ready
+	orderStatus, ticketStatus, err := lockTicket(ctx, tx, ticket)
+	if err != nil {
+		return false, err
+	}
+	if orderStatus != "cooking" || ticketStatus != "cooking" {
+		return false, fmt.Errorf("station ticket %d of order %d has unexpected status %s/%s", ticket.TicketID, ticket.Order.ID, ticketStatus, orderStatus)
+	}
+
+	const updateTicketSQL = `
+		UPDATE order_station_tickets
+		SET status = 'done', worker = $2, done_at = now(), updated_at = now()
+		WHERE id = $1;
+	`
+	if _, err := tx.Exec(ctx, updateTicketSQL, ticket.TicketID, workerName); err != nil {
+		return false, err
+	}
+
+	// Increment worker’s orders_processed count, every ticket counts
+	const updateWorkerSQL = `
+		UPDATE workers
+		SET orders_processed = orders_processed + 1, last_seen = now()
+		WHERE name = $1;
+	`
+	res, err := tx.Exec(ctx, updateWorkerSQL, workerName)
+	if err != nil {
+		return false, err
+	}
+	if res.RowsAffected() == 0 {
+		return false, fmt.Errorf("worker %s not found", workerName)
+	}
+
+	const insertStatusLogSQL = `
+		INSERT INTO order_status_log (order_id, status, changed_by, notes)
+		VALUES ($1, $2, $3, $4);
+	`
+	notes := fmt.Sprintf("Station %s done", ticket.Station)
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, ticket.Order.ID, "cooking", workerName, notes); err != nil {
+		return false, err
+	}
+
+	const remainingSQL = `
+		SELECT count(*) FROM order_station_tickets
+		WHERE order_id = $1 AND status IN ('pending', 'cooking');
+	`
+	var remaining int
+	if err := tx.QueryRow(ctx, remainingSQL, ticket.Order.ID).Scan(&remaining); err != nil {
+		return false, err
+	}
+	if remaining > 0 {
+		return false, tx.Commit(ctx)
+	}
+
+	// Every station is done, the order is ready
+	const updateOrderSQL = `
+		UPDATE orders
+		SET status = 'ready', completed_at = now(), updated_at = now()
+		WHERE id = $1;
+	`
+	if _, err := tx.Exec(ctx, updateOrderSQL, ticket.Order.ID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, insertStatusLogSQL, ticket.Order.ID, "ready", workerName, "All stations done"); err != nil {
+		return false, err
+	}
+
+	// Ready delivery orders are queued for the couriers with all their items
+	if ticket.Order.Type == "delivery" {
+		order, err := getOrder(ctx, tx, ticket.Order.Number)
+		if err != nil {
+			return false, err
+		}
+		if err := insertDeliveryOutbox(ctx, tx, &order); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	ticket.Order.Status = "ready"
+	return true, nil
+}
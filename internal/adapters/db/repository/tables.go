@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// checkTable rejects dine_in orders for a table that does not exist at the order's location or is closed.
+// The table row stays locked until the transaction ends, so only one session of a table is opened at a time.
+func checkTable(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	if order.Type != "dine_in" || order.TableNumber == nil {
+		return nil
+	}
+
+	var active bool
+	const selectSQL = `
+		SELECT active FROM dining_tables WHERE location_id = $1 AND number = $2 FOR UPDATE;
+	`
+	err := tx.QueryRow(ctx, selectSQL, order.LocationID, *order.TableNumber).Scan(&active)
+	if err == pgx.ErrNoRows {
+		return domain.ValidationErrors{{Field: "table_number", Code: domain.CodeUnknown, Message: fmt.Sprintf("table %d does not exist at location %s", *order.TableNumber, order.LocationID)}}
+	} else if err != nil {
+		return err
+	}
+	if !active {
+		return domain.ValidationErrors{{Field: "table_number", Code: domain.CodeUnavailable, Message: fmt.Sprintf("table %d is closed", *order.TableNumber)}}
+	}
+	return nil
+}
+
+// joinTableSession adds a dine_in order to the open session of its table, the first order opens it
+func joinTableSession(ctx context.Context, tx pgx.Tx, order *domain.Order) error {
+	if order.Type != "dine_in" || order.TableNumber == nil {
+		return nil
+	}
+
+	// The table lock serializes opening sessions, the session lock keeps it open until the order is saved
+	const lockTableSQL = `
+		SELECT 1 FROM dining_tables WHERE location_id = $1 AND number = $2 FOR UPDATE;
+	`
+	if _, err := tx.Exec(ctx, lockTableSQL, order.LocationID, *order.TableNumber); err != nil {
+		return err
+	}
+
+	const selectSQL = `
+		SELECT id FROM table_sessions
+		WHERE location_id = $1 AND table_number = $2 AND closed_at IS NULL
+		FOR UPDATE;
+	`
+	var sessionID int
+	err := tx.QueryRow(ctx, selectSQL, order.LocationID, *order.TableNumber).Scan(&sessionID)
+	if err == pgx.ErrNoRows {
+		const insertSQL = `
+			INSERT INTO table_sessions (location_id, table_number)
+			VALUES ($1, $2)
+			RETURNING id;
+		`
+		err = tx.QueryRow(ctx, insertSQL, order.LocationID, *order.TableNumber).Scan(&sessionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	order.TableSessionID = &sessionID
+	return nil
+}
+
+// ListOpenTables returns the tables with an open session, an empty locationID matches every location
+func (r *Repository) ListOpenTables(ctx context.Context, locationID string) ([]domain.OpenTable, error) {
+	const selectSQL = `
+		SELECT s.id, s.location_id, s.table_number, t.seats, s.opened_at, count(o.id),
+			COALESCE(sum(o.total_amount) FILTER (
+				WHERE o.status <> 'cancelled' AND NOT EXISTS (
+					SELECT 1 FROM payments p
+					WHERE p.order_id = o.id AND p.kind = 'charge' AND p.status = 'succeeded'
+				)
+			), 0)
+		FROM table_sessions s
+		JOIN dining_tables t ON t.location_id = s.location_id AND t.number = s.table_number
+		LEFT JOIN orders o ON o.table_session_id = s.id
+		WHERE s.closed_at IS NULL AND ($1 = '' OR s.location_id = $1)
+		GROUP BY s.id, t.seats
+		ORDER BY s.location_id, s.table_number;
+	`
+	rows, err := r.Conn.Query(ctx, selectSQL, locationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []domain.OpenTable{}
+	for rows.Next() {
+		var table domain.OpenTable
+		if err := rows.Scan(&table.SessionID, &table.LocationID, &table.TableNumber, &table.Seats, &table.OpenedAt, &table.Orders, &table.TotalAmount); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+// CloseTableSession closes the open session of the table and returns its combined bill.
+// All orders of the session must be ready or cancelled.
+func (r *Repository) CloseTableSession(ctx context.Context, locationID string, tableNumber int) (domain.TableBill, error) {
+	var bill domain.TableBill
+
+	tx, err := r.Conn.Begin(ctx)
+	if err != nil {
+		return bill, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the session so that no order joins it while it is closed
+	const selectSessionSQL = `
+		SELECT s.id, s.location_id, s.table_number, t.seats, s.opened_at
+		FROM table_sessions s
+		JOIN dining_tables t ON t.location_id = s.location_id AND t.number = s.table_number
+		WHERE s.location_id = $1 AND s.table_number = $2 AND s.closed_at IS NULL
+		FOR UPDATE OF s;
+	`
+	err = tx.QueryRow(ctx, selectSessionSQL, locationID, tableNumber).Scan(&bill.SessionID, &bill.LocationID, &bill.TableNumber, &bill.Seats, &bill.OpenedAt)
+	if err == pgx.ErrNoRows {
+		return bill, domain.ErrTableNotOpen
+	} else if err != nil {
+		return bill, err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT number FROM orders WHERE table_session_id = $1 ORDER BY created_at, id`, bill.SessionID)
+	if err != nil {
+		return bill, err
+	}
+	numbers, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return bill, err
+	}
+
+	bill.Orders = []domain.BillOrder{}
+	bill.TotalAmount = domain.NewMoney(0)
+	var inProgress []string
+	for _, number := range numbers {
+		order, err := getOrder(ctx, tx, number)
+		if err != nil {
+			return bill, err
+		}
+		// Orders paid online are listed but not billed again
+		paid, err := orderIsPaid(ctx, tx, order.ID, 0)
+		if err != nil {
+			return bill, err
+		}
+		switch order.Status {
+		case "ready":
+			if !paid {
+				bill.TotalAmount = bill.TotalAmount.Add(order.TotalAmount)
+			}
+		case "cancelled":
+		default:
+			inProgress = append(inProgress, fmt.Sprintf("%s (%s)", order.Number, order.Status))
+		}
+		bill.Orders = append(bill.Orders, domain.BillOrder{
+			OrderNumber:    order.Number,
+			Status:         order.Status,
+			Paid:           paid,
+			Items:          order.Items,
+			DiscountAmount: order.DiscountAmount,
+			TotalAmount:    order.TotalAmount,
+		})
+	}
+	if len(inProgress) > 0 {
+		return bill, fmt.Errorf("%w: %v", domain.ErrTableOrdersInProgress, inProgress)
+	}
+
+	const updateSQL = `
+		UPDATE table_sessions
+		SET closed_at = now(), total_amount = $2
+		WHERE id = $1
+		RETURNING closed_at;
+	`
+	if err := tx.QueryRow(ctx, updateSQL, bill.SessionID, bill.TotalAmount).Scan(&bill.ClosedAt); err != nil {
+		return bill, err
+	}
+
+	return bill, tx.Commit(ctx)
+}
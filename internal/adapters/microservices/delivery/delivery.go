@@ -0,0 +1,182 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wheres-my-pizza/internal/adapters/db/repository"
+	"wheres-my-pizza/internal/adapters/rabbitmq"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/ports"
+	"wheres-my-pizza/internal/core/services"
+	"wheres-my-pizza/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DeliveryService struct {
+	repo         *repository.Repository
+	rabbit       *rabbitmq.CourierRabbit
+	courierFlags services.CourierFlags
+	logger       *logger.Logger
+}
+
+var _ ports.DeliveryServiceInterface = (*DeliveryService)(nil)
+
+func NewDelivery(repo *repository.Repository, rabbit *rabbitmq.CourierRabbit, courierFlags services.CourierFlags, logger *logger.Logger) *DeliveryService {
+	return &DeliveryService{repo: repo, rabbit: rabbit, courierFlags: courierFlags, logger: logger}
+}
+
+func (d *DeliveryService) Start(ctx context.Context) error {
+	status, err := d.repo.GetCourierStatus(ctx, d.courierFlags.CourierName)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	switch status {
+	case "online":
+		err := fmt.Errorf("courier is already working")
+		d.logger.Error("", "courier_registration_failed", "Courier name is a duplicate", err, map[string]interface{}{"courier_name": d.courierFlags.CourierName})
+		return err
+	case "offline":
+		err := d.repo.UpdateCourierStatus(ctx, d.courierFlags.CourierName, "online")
+		if err != nil {
+			return err
+		}
+		// A returning courier may have moved to another restaurant
+		err = d.repo.UpdateCourierLocation(ctx, d.courierFlags.CourierName, d.courierFlags.LocationID)
+		if err != nil {
+			return err
+		}
+	case "":
+		err := d.repo.InsertCourier(ctx, d.courierFlags.CourierName, d.courierFlags.LocationID)
+		if err != nil {
+			return err
+		}
+	}
+	d.logger.Info("", "courier_registered", "Successfully registered courier", map[string]interface{}{"courier_name": d.courierFlags.CourierName, "location_id": d.courierFlags.LocationID})
+
+	errCh := make(chan error)
+	orderCh, err := d.rabbit.ConsumeMessages(ctx, errCh)
+	if err != nil {
+		return err
+	}
+
+	go d.deliverOrders(ctx, orderCh, errCh)
+
+	newErrCh := make(chan error)
+	go d.courierHeartbeat(ctx, time.Duration(d.courierFlags.HeartbeatInterval), newErrCh)
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-newErrCh:
+		return err
+	}
+}
+
+func (d *DeliveryService) deliverOrders(ctx context.Context, orderCh <-chan domain.Order, errCh chan error) {
+	for {
+		select {
+		case order := <-orderCh:
+			err := d.repo.OrderIsOutForDelivery(ctx, d.courierFlags.CourierName, &order)
+			if errors.Is(err, domain.ErrOrderCancelled) || errors.Is(err, domain.ErrOrderDelivered) {
+				d.logger.Info(order.Number, "delivery_dropped", "Order is not waiting for a courier anymore", map[string]interface{}{"courier_name": d.courierFlags.CourierName, "reason": err.Error()})
+				errCh <- nil
+				continue
+			} else if err != nil {
+				errCh <- err
+				continue
+			}
+			d.logger.Info(order.Number, "order_picked_up", "Order is out for delivery", map[string]interface{}{"courier_name": d.courierFlags.CourierName, "delivery_address": order.DeliveryAddress})
+
+			err = d.publishStatusUpdate(ctx, order, "ready", services.DeliveryTimeSeconds)
+			if err != nil {
+				errCh <- err
+				continue
+			}
+
+			// Simulating the ride to the customer
+			d.simulateDelivery(ctx, services.DeliveryTimeSeconds)
+
+			err = d.repo.OrderIsDelivered(ctx, d.courierFlags.CourierName, &order)
+			if errors.Is(err, domain.ErrOrderDelivered) {
+				errCh <- nil
+				continue
+			} else if err != nil {
+				errCh <- err
+				continue
+			}
+			d.logger.Info(order.Number, "order_delivered", "Order is delivered", map[string]interface{}{"courier_name": d.courierFlags.CourierName})
+
+			err = d.publishStatusUpdate(ctx, order, "out_for_delivery", 0)
+			errCh <- err
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publishStatusUpdate sends the status update, a missing notification subscriber does not fail the delivery
+func (d *DeliveryService) publishStatusUpdate(ctx context.Context, order domain.Order, oldStatus string, deliveryTime int) error {
+	err := d.rabbit.PublishStatusUpdateMessage(ctx, order, oldStatus, deliveryTime)
+	if errors.Is(err, domain.ErrPublishUnroutable) {
+		d.logger.Info(order.Number, "notification_unroutable", "No notification subscriber is bound, the status update is dropped", map[string]interface{}{"courier_name": d.courierFlags.CourierName, "new_status": order.Status})
+		return nil
+	}
+	return err
+}
+
+func (d *DeliveryService) simulateDelivery(ctx context.Context, deliveryTime int) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	counter := 0
+	fmt.Print("delivering")
+Loop:
+	for {
+		select {
+		case <-ticker.C:
+			if counter == deliveryTime {
+				fmt.Println()
+				break Loop
+			}
+			counter++
+			fmt.Print(".")
+		case <-ctx.Done():
+			return
+		}
+	}
+	fmt.Println("delivered!")
+}
+
+func (d *DeliveryService) courierHeartbeat(ctx context.Context, interval time.Duration, errCh chan error) {
+	ticker := time.NewTicker(interval * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.logger.Debug("", "heartbeat_sent", "Heartbeat is successfully sent", map[string]interface{}{"courier_name": d.courierFlags.CourierName})
+			err := d.repo.UpdateCourierHeartbeat(ctx, d.courierFlags.CourierName)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+func (d *DeliveryService) Stop(ctx context.Context) {
+	<-ctx.Done()
+	d.logger.Info("", "graceful_shutdown", "Courier starts its shutdown sequence", map[string]interface{}{"courier_name": d.courierFlags.CourierName})
+	err := d.repo.UpdateCourierStatus(context.Background(), d.courierFlags.CourierName, "offline")
+	if err != nil {
+		fmt.Printf("db cannot gracefully shutdown: %v\n", err)
+	}
+	d.repo.Conn.Close()
+	d.rabbit.Close()
+
+	fmt.Println("shutting down gracefully...")
+}
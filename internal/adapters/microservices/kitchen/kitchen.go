@@ -2,6 +2,7 @@ package kitchen
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 	"wheres-my-pizza/internal/adapters/db/repository"
@@ -42,22 +43,30 @@ func (k *KitchenService) Start(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		// A returning worker may have moved to another restaurant or station
+		err = k.repo.UpdateWorkerLocation(ctx, k.kitchenFlags.WorkerName, k.kitchenFlags.LocationID)
+		if err != nil {
+			return err
+		}
+		err = k.repo.UpdateWorkerStations(ctx, k.kitchenFlags.WorkerName, k.kitchenFlags.Stations)
+		if err != nil {
+			return err
+		}
 	case "":
-		err := k.repo.InsertWorker(ctx, k.kitchenFlags.WorkerName, k.kitchenFlags.OrderTypes)
+		err := k.repo.InsertWorker(ctx, k.kitchenFlags.WorkerName, k.kitchenFlags.OrderTypes, k.kitchenFlags.Stations, k.kitchenFlags.LocationID)
 		if err != nil {
 			return err
 		}
 	}
-	k.logger.Info("", "worker_registered", "Successfully registered worker", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName})
+	k.logger.Info("", "worker_registered", "Successfully registered worker", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "location_id": k.kitchenFlags.LocationID, "stations": k.kitchenFlags.Stations})
 
 	errCh := make(chan error)
-	orderCh := make(chan domain.Order)
-	orderCh, err = k.rabbit.ConsumeMessages(ctx, k.kitchenFlags.WorkerName, errCh)
+	ticketCh, err := k.rabbit.ConsumeMessages(ctx, k.kitchenFlags.WorkerName, errCh)
 	if err != nil {
 		return err
 	}
 
-	go k.getOrder(ctx, orderCh, errCh)
+	go k.getOrder(ctx, ticketCh, errCh)
 
 	newErrCh := make(chan error)
 	go k.workerHeartbeat(ctx, time.Duration(k.kitchenFlags.HeartbeatInterval), newErrCh)
@@ -70,39 +79,86 @@ func (k *KitchenService) Start(ctx context.Context) error {
 	}
 }
 
-func (k *KitchenService) getOrder(ctx context.Context, orderCh <-chan domain.Order, errCh chan error) {
+func (k *KitchenService) getOrder(ctx context.Context, ticketCh <-chan domain.StationTicketMessage, errCh chan error) {
 	for {
 		select {
-		case order := <-orderCh:
-			err := k.repo.OrderIsCooking(ctx, k.kitchenFlags.WorkerName, &order)
+		case ticket := <-ticketCh:
+			order := ticket.Order
+			// Cancelled orders are dropped, the message is acknowledged
+			status, err := k.repo.GetOrderStatus(ctx, order.ID)
 			if err != nil {
 				errCh <- err
+				continue
 			}
-
-			var cookingTime int
-			switch order.Type {
-			case "dine_in":
-				cookingTime = 8
-			case "takeout":
-				cookingTime = 10
-			case "delivery":
-				cookingTime = 12
+			if status == "cancelled" {
+				k.logger.Info(order.Number, "order_dropped", "Order was cancelled before cooking", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "station": ticket.Station})
+				errCh <- nil
+				continue
 			}
 
-			err = k.rabbit.PublishStatusUpdateMessage(ctx, order, "received", k.kitchenFlags.WorkerName, cookingTime)
-			if err != nil {
+			// Received, paid or already cooking at another station
+			oldStatus, err := k.repo.TicketIsCooking(ctx, k.kitchenFlags.WorkerName, &ticket)
+			if errors.Is(err, domain.ErrOrderCancelled) {
+				k.logger.Info(order.Number, "order_dropped", "Order was cancelled before cooking", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "station": ticket.Station})
+				errCh <- nil
+				continue
+			} else if errors.Is(err, domain.ErrStaleOrderMessage) {
+				k.logger.Info(order.Number, "order_dropped", "Order was modified, a newer version is queued", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "version": order.Version, "station": ticket.Station})
+				errCh <- nil
+				continue
+			} else if errors.Is(err, domain.ErrTicketDone) {
+				k.logger.Info(order.Number, "ticket_dropped", "Station ticket was already cooked", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "ticket_id": ticket.TicketID, "station": ticket.Station})
+				errCh <- nil
+				continue
+			} else if err != nil {
 				errCh <- err
+				continue
+			}
+
+			// The ticket lists the items of this station with their modifiers, e.g. "1x Margherita Pizza (Large, No Olives)"
+			lines := services.KitchenTicket(ticket.Order)
+			k.logger.Info(order.Number, "order_ticket", "Station ticket is printed", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "station": ticket.Station, "ticket": lines})
+			fmt.Printf("ticket %s [%s]:\n", order.Number, ticket.Station)
+			for _, line := range lines {
+				fmt.Println("  " + line)
+			}
+
+			cookingTime := services.CookingTimeSeconds(order.Type)
+
+			// Only the first station moves the order to cooking, the estimate comes from the recent cook durations
+			if oldStatus != "cooking" {
+				estimate, err := k.repo.CookSeconds(ctx, order.Type, order.LocationID)
+				if err != nil {
+					errCh <- err
+					continue
+				}
+				err = k.publishStatusUpdate(ctx, ticket.Order, oldStatus, estimate)
+				if err != nil {
+					errCh <- err
+					continue
+				}
 			}
 
 			// Simulating work of workers
 			k.simulateWork(ctx, cookingTime)
 
-			err = k.repo.OrderIsReady(ctx, k.kitchenFlags.WorkerName, &order)
-			if err != nil {
+			ready, err := k.repo.TicketIsDone(ctx, k.kitchenFlags.WorkerName, &ticket)
+			if errors.Is(err, domain.ErrOrderCancelled) {
+				k.logger.Info(order.Number, "order_dropped", "Order was cancelled by a manager while cooking", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "station": ticket.Station})
+				errCh <- nil
+				continue
+			} else if err != nil {
 				errCh <- err
+				continue
+			}
+			if !ready {
+				// Other stations of the order are still cooking
+				errCh <- nil
+				continue
 			}
 
-			err = k.rabbit.PublishStatusUpdateMessage(ctx, order, "cooking", k.kitchenFlags.WorkerName, cookingTime)
+			// The order is completed now
+			err = k.publishStatusUpdate(ctx, ticket.Order, "cooking", 0)
 			errCh <- err
 		case <-ctx.Done():
 			return
@@ -110,6 +166,16 @@ func (k *KitchenService) getOrder(ctx context.Context, orderCh <-chan domain.Ord
 	}
 }
 
+// publishStatusUpdate sends the status update, a missing notification subscriber does not fail the order
+func (k *KitchenService) publishStatusUpdate(ctx context.Context, order domain.Order, oldStatus string, cookingTime int) error {
+	err := k.rabbit.PublishStatusUpdateMessage(ctx, order, oldStatus, k.kitchenFlags.WorkerName, cookingTime)
+	if errors.Is(err, domain.ErrPublishUnroutable) {
+		k.logger.Info(order.Number, "notification_unroutable", "No notification subscriber is bound, the status update is dropped", map[string]interface{}{"worker_name": k.kitchenFlags.WorkerName, "new_status": order.Status})
+		return nil
+	}
+	return err
+}
+
 func (k *KitchenService) simulateWork(ctx context.Context, cookingTime int) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
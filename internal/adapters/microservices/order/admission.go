@@ -0,0 +1,48 @@
+package order
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// admission limits the number of in-flight orders to maxConcurrent.
+// Requests wait up to maxWait for a free slot and are rejected afterwards.
+type admission struct {
+	slots    chan struct{}
+	maxWait  time.Duration
+	inFlight atomic.Int64
+	rejected atomic.Int64
+}
+
+func newAdmission(maxConcurrent int, maxWait time.Duration) *admission {
+	return &admission{slots: make(chan struct{}, maxConcurrent), maxWait: maxWait}
+}
+
+// acquire returns false if no slot became free in time, otherwise release must be called
+func (a *admission) acquire(ctx context.Context) bool {
+	select {
+	case a.slots <- struct{}{}:
+		a.inFlight.Add(1)
+		return true
+	default:
+	}
+
+	timer := time.NewTimer(a.maxWait)
+	defer timer.Stop()
+
+	select {
+	case a.slots <- struct{}{}:
+		a.inFlight.Add(1)
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	a.rejected.Add(1)
+	return false
+}
+
+func (a *admission) release() {
+	a.inFlight.Add(-1)
+	<-a.slots
+}
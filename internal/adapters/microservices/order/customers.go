@@ -0,0 +1,62 @@
+package order
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// POST /customers
+func (o *OrderService) PostCustomer(w http.ResponseWriter, r *http.Request) {
+	var customer domain.Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot decode the customer", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	if errs := services.CheckCustomerValues(customer, ""); len(errs) > 0 {
+		o.logger.Error("", "validation_failed", "The customer data failed validation step", errs, nil)
+		services.WriteValidationProblem(w, errs)
+		return
+	}
+
+	err := o.repo.CreateCustomer(r.Context(), &customer)
+	if errors.Is(err, domain.ErrCustomerExists) {
+		services.WriteProblem(w, http.StatusConflict, err.Error(), nil)
+		return
+	} else if err != nil {
+		o.logger.Error("", "db_query_failed", "Cannot insert the customer", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot insert the customer to db: "+err.Error(), nil)
+		return
+	}
+
+	o.logger.Info("", "customer_created", "New customer is registered", map[string]interface{}{"customer_id": customer.ID})
+	services.WriteJSON(w, customer, http.StatusCreated)
+}
+
+// GET /customers/{id}
+func (o *OrderService) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		services.WriteProblem(w, http.StatusBadRequest, "customer id must be a positive number", nil)
+		return
+	}
+
+	customer, err := o.repo.GetCustomer(r.Context(), id)
+	if err == pgx.ErrNoRows {
+		services.WriteProblem(w, http.StatusNotFound, "customer was not found", nil)
+		return
+	} else if err != nil {
+		o.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get the customer: "+err.Error(), nil)
+		return
+	}
+
+	services.WriteJSON(w, customer, http.StatusOK)
+}
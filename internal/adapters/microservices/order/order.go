@@ -3,29 +3,55 @@ package order
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 	"wheres-my-pizza/internal/adapters/db/repository"
 	"wheres-my-pizza/internal/adapters/rabbitmq"
+	"wheres-my-pizza/internal/adapters/spool"
 	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/internal/core/ports"
 	"wheres-my-pizza/internal/core/services"
 	"wheres-my-pizza/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
 )
 
+const maxBatchSize = 100
+
 type OrderService struct {
 	maxConcurrent int
 	port          int
 	repo          *repository.Repository
 	rabbit        *rabbitmq.OrderRabbit
 	logger        *logger.Logger
+	outboxWakeCh  chan struct{}
+	admission     *admission
+	schedule      services.ScheduleRules
+	spool         *spool.Spool
+	payments      ports.PaymentProvider
 }
 
 var _ ports.OrderServiceInterface = (*OrderService)(nil)
 
-func NewOrderHandler(repo *repository.Repository, rabbit *rabbitmq.OrderRabbit, maxConcurrent, port int, logger *logger.Logger) *OrderService {
-	return &OrderService{maxConcurrent: maxConcurrent, rabbit: rabbit, port: port, repo: repo, logger: logger}
+func NewOrderHandler(repo *repository.Repository, rabbit *rabbitmq.OrderRabbit, orderFlags services.OrderFlags, schedule services.ScheduleRules, spool *spool.Spool, payments ports.PaymentProvider, logger *logger.Logger) *OrderService {
+	return &OrderService{
+		maxConcurrent: orderFlags.MaxConcurrent,
+		rabbit:        rabbit,
+		port:          orderFlags.Port,
+		repo:          repo,
+		logger:        logger,
+		outboxWakeCh:  make(chan struct{}, 1),
+		admission:     newAdmission(orderFlags.MaxConcurrent, time.Duration(orderFlags.MaxWaitMs)*time.Millisecond),
+		schedule:      schedule,
+		spool:         spool,
+		payments:      payments,
+	}
 }
 
 func (o *OrderService) Stop(ctx context.Context, server *http.Server) {
@@ -44,51 +70,425 @@ func (o *OrderService) PostOrder(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var order domain.Order
 
-	err := json.NewDecoder(r.Body).Decode(&order)
+	// Admission control, at most maxConcurrent orders are processed at once
+	if !o.admission.acquire(ctx) {
+		o.rejectBusy(w)
+		return
+	}
+	defer o.admission.release()
+
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		// ERROR LOGGER
-		http.Error(w, "Cannot decode the order", http.StatusBadRequest)
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot read the request body", nil)
 		return
 	}
 	defer r.Body.Close()
 
-	err = services.CheckOrderValues(order)
+	err = json.Unmarshal(body, &order)
 	if err != nil {
-		o.logger.Error("", "validation_failed", "The order data failed validation step", err, nil)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		// ERROR LOGGER
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot decode the order", nil)
 		return
 	}
-	o.logger.Debug("", "order_received", "New valid order is received", nil)
 
-	orderNumber, err := o.repo.InsertOrder(ctx, &order)
+	// Idempotency-Key is optional, retries with the same key get the original response
+	var idemKey *domain.IdempotencyKey
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		if len(key) > 255 {
+			services.WriteProblem(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters", nil)
+			return
+		}
+		idemKey = &domain.IdempotencyKey{Key: key, RequestHash: services.HashRequestBody(body)}
+	}
+
+	menu, err := o.repo.GetMenuItems(ctx, services.GetItemSKUs(order.Items))
 	if err != nil {
+		o.logger.Error("", "db_query_failed", "Cannot load the menu items", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot load the menu: "+err.Error(), nil)
+		return
+	}
+	modifiers, err := o.repo.GetMenuModifiers(ctx, services.GetModifierCodes(order.Items))
+	if err != nil {
+		o.logger.Error("", "db_query_failed", "Cannot load the menu modifiers", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot load the menu: "+err.Error(), nil)
+		return
+	}
+
+	err = o.checkOrder(order, menu, modifiers)
+	if err != nil {
+		o.logger.Error("", "validation_failed", "The order data failed validation step", err, nil)
+		services.WriteValidationProblem(w, err)
+		return
+	}
+	o.logger.Debug("", "order_received", "New valid order is received", map[string]interface{}{"in_flight": o.admission.inFlight.Load(), "rejected_total": o.admission.rejected.Load()})
+
+	var validationErrs domain.ValidationErrors
+	orderNumber, err := o.repo.InsertOrder(ctx, &order, idemKey)
+	if errors.As(err, &validationErrs) {
+		services.WriteValidationProblem(w, err)
+		return
+	} else if errors.Is(err, domain.ErrIdempotencyKeyReused) {
+		o.logger.Error("", "idempotency_key_conflict", "Idempotency key is reused with a different request body", err, map[string]interface{}{"idempotency_key": idemKey.Key})
+		services.WriteProblem(w, http.StatusConflict, err.Error(), nil)
+		return
+	} else if err != nil {
 		o.logger.Error("", "db_transaction_failed", "The transaction of order data into db is failed", err, nil)
-		http.Error(w, "Cannot insert the order to db: "+err.Error(), http.StatusInternalServerError)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot insert the order to db: "+err.Error(), nil)
+		return
+	}
+
+	// Replayed request, the order message was already queued by the original one
+	if idemKey != nil && idemKey.Replayed {
+		o.logger.Debug(orderNumber, "order_replayed", "Returning the stored response for the idempotency key", map[string]interface{}{"idempotency_key": idemKey.Key})
+		services.WriteJSON(w, idemKey.Response, http.StatusOK)
+		return
+	}
+
+	o.logger.Debug(orderNumber, "priority_assigned", "Priority is assigned to the order", map[string]interface{}{"priority": order.Priority, "reasons": order.PriorityReasons})
+
+	response := services.NewPutOrderResponse(&order)
+
+	// The order message was queued in the same transaction, publish it with a confirm.
+	// The order is saved either way, failed messages are retried by the outbox relay.
+	// Online orders are queued only once they are paid, there is nothing to publish yet.
+	status := http.StatusOK
+	if err := o.publishOrderNow(ctx, order.ID); err != nil {
+		status = http.StatusAccepted
+		response.DispatchError = o.dispatchError(orderNumber, err)
+	}
+
+	services.WriteJSON(w, response, status)
+}
+
+// dispatchError explains to the client why a saved order is not in the kitchen queue yet
+func (o *OrderService) dispatchError(orderNumber string, err error) string {
+	switch {
+	case errors.Is(err, errSpooled):
+		return "the kitchen queue is unavailable, the order is spooled and sent when it is back"
+	case errors.Is(err, domain.ErrPublishUnroutable):
+		return "no kitchen queue accepts this order yet, it is sent when a worker of its location and type starts"
+	case errors.Is(err, domain.ErrPublishNacked), errors.Is(err, domain.ErrPublishTimeout), errors.Is(err, domain.ErrBrokerUnavailable):
+		return "the kitchen queue is unavailable, the order is sent when it is back"
+	default:
+		o.logger.Error(orderNumber, "outbox_publish_failed", "Cannot publish the order message", err, nil)
+		return "the order is sent to the kitchen later"
+	}
+}
+
+// POST /orders/batch?mode=all_or_nothing|best_effort
+func (o *OrderService) PostOrderBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = domain.BatchModeBestEffort
+	}
+	if mode != domain.BatchModeAllOrNothing && mode != domain.BatchModeBestEffort {
+		services.WriteProblem(w, http.StatusBadRequest, "invalid mode: must be one of [all_or_nothing, best_effort]", nil)
 		return
 	}
 
-	err = o.rabbit.PublishOrderMessage(ctx, order)
+	// The whole batch takes one admission slot
+	if !o.admission.acquire(ctx) {
+		o.rejectBusy(w)
+		return
+	}
+	defer o.admission.release()
+
+	var orders []domain.Order
+	if err := json.NewDecoder(r.Body).Decode(&orders); err != nil {
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot decode the orders", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(orders) < 1 || len(orders) > maxBatchSize {
+		services.WriteValidationProblem(w, domain.ValidationErrors{{Field: "", Code: domain.CodeOutOfRange, Message: fmt.Sprintf("orders count is invalid: got %d, allowed 1 - %d", len(orders), maxBatchSize)}})
+		return
+	}
+
+	var items []domain.OrderItem
+	for _, order := range orders {
+		items = append(items, order.Items...)
+	}
+	menu, err := o.repo.GetMenuItems(ctx, services.GetItemSKUs(items))
+	if err != nil {
+		o.logger.Error("", "db_query_failed", "Cannot load the menu items", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot load the menu: "+err.Error(), nil)
+		return
+	}
+	modifiers, err := o.repo.GetMenuModifiers(ctx, services.GetModifierCodes(items))
 	if err != nil {
-		o.logger.Error("", "rabbitmq_publish_failed", "The publishing of the order message failed.", err, nil)
-		http.Error(w, "Cannot publish order message: "+err.Error(), http.StatusInternalServerError)
+		o.logger.Error("", "db_query_failed", "Cannot load the menu modifiers", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot load the menu: "+err.Error(), nil)
 		return
 	}
-	o.logger.Debug("", "order_published", "The order is successfully published to RabbitMQ", nil)
 
-	response := domain.PutOrderResponse{
-		OrderNumber: orderNumber,
-		Status:      "received",
-		TotalAmount: order.TotalAmount,
+	// Validating every order, only valid ones go to the database
+	response := domain.BatchOrderResponse{Mode: mode, Results: make([]domain.BatchOrderResult, len(orders))}
+	var valid []*domain.Order
+	var validIdx []int
+	for i := range orders {
+		response.Results[i] = domain.BatchOrderResult{Index: i, Status: "not_committed"}
+		if err := o.checkOrder(orders[i], menu, modifiers); err != nil {
+			response.Results[i].Status = "rejected"
+			response.Results[i].Errors = services.FieldErrors(err)
+			continue
+		}
+		valid = append(valid, &orders[i])
+		validIdx = append(validIdx, i)
 	}
 
-	responseByte, err := json.Marshal(response)
+	allOrNothing := mode == domain.BatchModeAllOrNothing
+	if allOrNothing && len(valid) != len(orders) {
+		o.logger.Info("", "batch_rejected", "The batch failed validation, nothing is saved", map[string]interface{}{"orders": len(orders), "invalid": len(orders) - len(valid)})
+		o.finishBatch(w, response, http.StatusUnprocessableEntity)
+		return
+	}
+
+	errs, err := o.repo.InsertOrders(ctx, valid, allOrNothing)
+	if err != nil && !errors.Is(err, domain.ErrBatchRolledBack) {
+		o.logger.Error("", "db_transaction_failed", "The transaction of the order batch failed", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot insert the orders to db: "+err.Error(), nil)
+		return
+	}
+
+	// A rolled back batch is only the client's fault when the failing order is invalid
+	if errors.Is(err, domain.ErrBatchRolledBack) {
+		var validationErrs domain.ValidationErrors
+		for _, orderErr := range errs {
+			if orderErr != nil && !errors.As(orderErr, &validationErrs) {
+				o.logger.Error("", "db_transaction_failed", "The transaction of the order batch failed", orderErr, nil)
+				services.WriteProblem(w, http.StatusInternalServerError, "Cannot insert the orders to db: "+orderErr.Error(), nil)
+				return
+			}
+		}
+	}
+
+	for j, order := range valid {
+		result := &response.Results[validIdx[j]]
+		switch {
+		case errs[j] != nil:
+			result.Status = "rejected"
+			result.Errors = services.FieldErrors(errs[j])
+		case errors.Is(err, domain.ErrBatchRolledBack):
+			result.Status = "not_committed"
+		default:
+			result.Status = order.Status
+			result.OrderNumber = order.Number
+			total := order.TotalAmount
+			result.TotalAmount = &total
+		}
+	}
+
+	// Order messages of committed orders were queued in their transactions
+	o.wakeOutboxRelay()
+
+	status := http.StatusOK
 	if err != nil {
-		// ERROR LOGGER
-		http.Error(w, "Cannot marshal the order", http.StatusInternalServerError)
+		status = http.StatusUnprocessableEntity
+	}
+	o.finishBatch(w, response, status)
+}
+
+func (o *OrderService) finishBatch(w http.ResponseWriter, response domain.BatchOrderResponse, status int) {
+	for _, result := range response.Results {
+		switch result.Status {
+		case "rejected":
+			response.Rejected++
+		case "not_committed":
+		default:
+			response.Committed++
+		}
+	}
+	o.logger.Info("", "batch_processed", "The order batch is processed", map[string]interface{}{"mode": response.Mode, "committed": response.Committed, "rejected": response.Rejected})
+	services.WriteJSON(w, response, status)
+}
+
+// GET /menu
+func (o *OrderService) GetMenu(w http.ResponseWriter, r *http.Request) {
+	menu, err := o.repo.GetMenu(r.Context())
+	if err != nil {
+		o.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get the menu: "+err.Error(), nil)
+		return
+	}
+
+	services.WriteJSON(w, menu, http.StatusOK)
+}
+
+// GET /menu/modifiers
+func (o *OrderService) GetMenuModifiers(w http.ResponseWriter, r *http.Request) {
+	modifiers, err := o.repo.ListMenuModifiers(r.Context())
+	if err != nil {
+		o.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get the menu modifiers: "+err.Error(), nil)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(responseByte)
+	services.WriteJSON(w, modifiers, http.StatusOK)
+}
+
+// PATCH /orders/{order_number}
+func (o *OrderService) ModifyOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderNumber := r.PathValue("order_number")
+
+	var req domain.ModifyOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot decode the modification request", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	order, err := o.repo.GetOrder(ctx, orderNumber)
+	if err == pgx.ErrNoRows {
+		services.WriteProblem(w, http.StatusNotFound, "order was not found", nil)
+		return
+	} else if err != nil {
+		o.logger.Error(orderNumber, "db_query_failed", "Database query failed", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot get the order: "+err.Error(), nil)
+		return
+	}
+	if order.Status != "received" && order.Status != "scheduled" && order.Status != "awaiting_payment" {
+		services.WriteProblem(w, http.StatusConflict, domain.ErrOrderNotModifiable.Error(), nil)
+		return
+	}
+
+	diff, err := services.ApplyOrderChanges(&order, req)
+	if err != nil {
+		o.logger.Error(orderNumber, "validation_failed", "The order modification is invalid", err, nil)
+		services.WriteValidationProblem(w, err)
+		return
+	}
+
+	menu, err := o.repo.GetMenuItems(ctx, services.GetItemSKUs(order.Items))
+	if err != nil {
+		o.logger.Error(orderNumber, "db_query_failed", "Cannot load the menu items", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot load the menu: "+err.Error(), nil)
+		return
+	}
+	modifiers, err := o.repo.GetMenuModifiers(ctx, services.GetModifierCodes(order.Items))
+	if err != nil {
+		o.logger.Error(orderNumber, "db_query_failed", "Cannot load the menu modifiers", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot load the menu: "+err.Error(), nil)
+		return
+	}
+
+	err = o.checkOrder(order, menu, modifiers)
+	if err != nil {
+		o.logger.Error(orderNumber, "validation_failed", "The modified order failed validation step", err, nil)
+		services.WriteValidationProblem(w, err)
+		return
+	}
+
+	var validationErrs domain.ValidationErrors
+	err = o.repo.UpdateOrderItems(ctx, &order, diff)
+	if errors.As(err, &validationErrs) {
+		services.WriteValidationProblem(w, err)
+		return
+	} else if errors.Is(err, domain.ErrOrderNotModifiable) || errors.Is(err, domain.ErrOrderModified) || errors.Is(err, domain.ErrOrderAlreadyPaid) {
+		services.WriteProblem(w, http.StatusConflict, err.Error(), nil)
+		return
+	} else if err != nil {
+		o.logger.Error(orderNumber, "db_transaction_failed", "The modification of the order failed", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot modify the order: "+err.Error(), nil)
+		return
+	}
+	o.logger.Info(orderNumber, "order_modified", "The order items are modified", map[string]interface{}{"diff": diff, "version": order.Version})
+
+	// The new version of the order was queued in the same transaction
+	o.wakeOutboxRelay()
+
+	response := services.NewPutOrderResponse(&order)
+	services.WriteJSON(w, response, http.StatusOK)
+}
+
+// POST /orders/{order_number}/cancel
+func (o *OrderService) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderNumber := r.PathValue("order_number")
+
+	var req domain.CancelOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot decode the cancel request", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) < 1 || len(req.Reason) > 200 {
+		services.WriteValidationProblem(w, domain.ValidationErrors{{Field: "reason", Code: domain.CodeOutOfRange, Message: "length must be 1 - 200"}})
+		return
+	}
+
+	oldStatus, err := o.repo.CancelOrder(ctx, orderNumber, req.Reason, req.ManagerOverride)
+	switch {
+	case err == pgx.ErrNoRows:
+		services.WriteProblem(w, http.StatusNotFound, "order was not found", nil)
+		return
+	case errors.Is(err, domain.ErrOrderCookingNoManager), errors.Is(err, domain.ErrOrderNotCancellable):
+		o.logger.Error(orderNumber, "order_cancel_rejected", "The order cannot be cancelled", err, map[string]interface{}{"status": oldStatus})
+		services.WriteProblem(w, http.StatusConflict, err.Error(), nil)
+		return
+	case err != nil:
+		o.logger.Error(orderNumber, "db_transaction_failed", "The cancellation of the order failed", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot cancel the order: "+err.Error(), nil)
+		return
+	}
+	o.logger.Info(orderNumber, "order_cancelled", "The order is cancelled", map[string]interface{}{"previous_status": oldStatus, "reason": req.Reason, "manager_override": req.ManagerOverride})
+
+	// Status update was queued in the same transaction
+	o.wakeOutboxRelay()
+
+	response := domain.CancelOrderResponse{
+		OrderNumber:    orderNumber,
+		PreviousStatus: oldStatus,
+		Status:         "cancelled",
+		Reason:         req.Reason,
+		Refunds:        o.sendRefunds(ctx, orderNumber),
+	}
+	services.WriteJSON(w, response, http.StatusOK)
+}
+
+// checkOrder runs CheckOrderValues and the scheduling rules, all violations are returned together
+func (o *OrderService) checkOrder(order domain.Order, menu map[string]domain.MenuItem, modifiers map[string]domain.MenuModifier) error {
+	var errs domain.ValidationErrors
+	if err := services.CheckOrderValues(order, menu, modifiers); err != nil {
+		errs = append(errs, services.FieldErrors(err)...)
+	}
+	if fieldErr := o.schedule.Check(order, time.Now()); fieldErr != nil {
+		errs = append(errs, *fieldErr)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// rejectBusy answers 503 when no admission slot became free in time
+func (o *OrderService) rejectBusy(w http.ResponseWriter) {
+	o.logger.Info("", "order_rejected", "Too many orders in flight, the request is rejected", map[string]interface{}{
+		"in_flight":      o.admission.inFlight.Load(),
+		"max_concurrent": o.maxConcurrent,
+		"rejected_total": o.admission.rejected.Load(),
+	})
+	w.Header().Set("Retry-After", strconv.Itoa(o.retryAfterSeconds()))
+	services.WriteProblem(w, http.StatusServiceUnavailable, "Order service is busy, please retry later", nil)
+}
+
+// retryAfterSeconds suggests a retry once the queue wait has passed, at least 1 second
+func (o *OrderService) retryAfterSeconds() int {
+	seconds := int(o.admission.maxWait.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+func (o *OrderService) wakeOutboxRelay() {
+	select {
+	case o.outboxWakeCh <- struct{}{}:
+	default:
+	}
 }
@@ -0,0 +1,145 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 50
+	outboxLease        = 30 * time.Second
+	outboxMaxBackoff   = time.Minute
+)
+
+// RelayOutbox publishes pending outbox rows to RabbitMQ until ctx is cancelled.
+// It wakes up on every poll tick and whenever PostOrder signals a new order.
+func (o *OrderService) RelayOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.outboxWakeCh:
+		}
+		o.relayOutboxBatch(ctx)
+	}
+}
+
+func (o *OrderService) relayOutboxBatch(ctx context.Context) {
+	messages, err := o.repo.ClaimOutboxMessages(ctx, outboxBatchSize, outboxLease)
+	if err != nil {
+		if ctx.Err() == nil {
+			o.logger.Error("", "outbox_claim_failed", "Cannot claim pending outbox messages", err, nil)
+		}
+		return
+	}
+
+	// A message no queue is bound for only holds back the later messages of its own order,
+	// they wait for the same backoff so that the order of an order's messages is kept
+	blocked := make(map[int]time.Duration)
+	for i, msg := range messages {
+		if backoff, ok := blocked[msg.OrderID]; ok {
+			if err := o.repo.DelayOutboxMessages(ctx, []int{msg.ID}, backoff); err != nil {
+				o.logger.Error("", "outbox_update_failed", "Cannot delay pending outbox messages", err, map[string]interface{}{"outbox_id": msg.ID})
+			}
+			continue
+		}
+
+		err := o.publishOutboxMessage(ctx, msg)
+		if err == nil || errors.Is(err, errSpooled) {
+			continue
+		}
+		if errors.Is(err, domain.ErrPublishUnroutable) && msg.Exchange == "orders_topic" {
+			blocked[msg.OrderID] = outboxBackoff(msg.Attempts + 1)
+			continue
+		}
+
+		// The broker fails for every message, the rest of the batch waits for the same backoff
+		var rest []int
+		for _, next := range messages[i+1:] {
+			rest = append(rest, next.ID)
+		}
+		if err := o.repo.DelayOutboxMessages(ctx, rest, outboxBackoff(msg.Attempts+1)); err != nil {
+			o.logger.Error("", "outbox_update_failed", "Cannot delay pending outbox messages", err, nil)
+		}
+		return
+	}
+}
+
+// publishOrderNow publishes the queued messages of a just saved order without waiting for the relay.
+// Failed messages stay in the outbox and are retried by the relay.
+func (o *OrderService) publishOrderNow(ctx context.Context, orderID int) error {
+	messages, err := o.repo.ClaimOrderOutboxMessages(ctx, orderID, outboxLease)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if err := o.publishOutboxMessage(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishOutboxMessage publishes one claimed message with a publisher confirm and records the outcome.
+// While RabbitMQ is unreachable the message goes to the disk spool and errSpooled is returned.
+func (o *OrderService) publishOutboxMessage(ctx context.Context, msg domain.OutboxMessage) error {
+	// Messages behind spooled ones are spooled too, so the drainer replays them in order
+	if !o.spool.Empty() {
+		if err := o.spoolMessage(ctx, msg, errors.New("older messages are spooled")); err == nil {
+			return errSpooled
+		}
+	}
+
+	err := o.rabbit.PublishOutboxMessage(ctx, msg)
+
+	if errors.Is(err, domain.ErrBrokerUnavailable) || errors.Is(err, domain.ErrPublishTimeout) {
+		if spoolErr := o.spoolMessage(ctx, msg, err); spoolErr == nil {
+			return errSpooled
+		}
+	}
+
+	// Nobody listens to status updates, there is nothing to retry
+	if errors.Is(err, domain.ErrPublishUnroutable) && msg.Exchange == "notifications_fanout" {
+		o.logger.Info("", "notification_unroutable", "No notification subscriber is bound, the status update is dropped", map[string]interface{}{"outbox_id": msg.ID})
+		err = nil
+	}
+
+	if err != nil {
+		backoff := outboxBackoff(msg.Attempts + 1)
+		action := "rabbitmq_publish_failed"
+		if errors.Is(err, domain.ErrPublishUnroutable) {
+			action = "rabbitmq_message_unroutable"
+		}
+		o.logger.Error("", action, "The publishing of the outbox message failed", err, map[string]interface{}{"outbox_id": msg.ID, "routing_key": msg.RoutingKey, "attempts": msg.Attempts + 1, "retry_in_ms": backoff.Milliseconds()})
+		if err := o.repo.MarkOutboxFailed(ctx, msg.ID, backoff, err); err != nil {
+			o.logger.Error("", "outbox_update_failed", "Cannot mark outbox message as failed", err, map[string]interface{}{"outbox_id": msg.ID})
+		}
+		return err
+	}
+
+	if err := o.repo.MarkOutboxSent(ctx, msg.ID); err != nil {
+		o.logger.Error("", "outbox_update_failed", "Cannot mark outbox message as sent", err, map[string]interface{}{"outbox_id": msg.ID})
+		return nil
+	}
+	o.logger.Debug("", "order_published", "The order is successfully published to RabbitMQ", map[string]interface{}{"outbox_id": msg.ID, "routing_key": msg.RoutingKey})
+	return nil
+}
+
+// outboxBackoff doubles the delay on every attempt: 1s, 2s, 4s ... up to outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,203 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	refundRetryInterval = time.Minute
+	refundBatchSize     = 50
+)
+
+// POST /orders/{order_number}/payments
+func (o *OrderService) PostPayment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderNumber := r.PathValue("order_number")
+
+	var req domain.PaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		services.WriteProblem(w, http.StatusBadRequest, "Cannot decode the payment", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	order, err := o.repo.GetOrder(ctx, orderNumber)
+	if err == pgx.ErrNoRows {
+		services.WriteProblem(w, http.StatusNotFound, "order was not found", nil)
+		return
+	} else if err != nil {
+		o.logger.Error(orderNumber, "db_query_failed", "Database query failed", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot get the order: "+err.Error(), nil)
+		return
+	}
+
+	var errs domain.ValidationErrors
+	if req.Token == "" {
+		errs = append(errs, domain.FieldError{Field: "token", Code: domain.CodeRequired, Message: "is required"})
+	} else if len(req.Token) > 200 {
+		errs = append(errs, domain.FieldError{Field: "token", Code: domain.CodeOutOfRange, Message: "length must be 1 - 200"})
+	}
+	if req.Amount != nil && req.Amount.Amount != order.TotalAmount.Amount {
+		errs = append(errs, domain.FieldError{Field: "amount", Code: domain.CodeInvalid, Message: "must equal the order total " + order.TotalAmount.String() + " (got " + req.Amount.String() + ")"})
+	}
+	if len(errs) > 0 {
+		services.WriteValidationProblem(w, errs)
+		return
+	}
+
+	// Only unpaid online orders are charged
+	if order.PaymentMethod != domain.PaymentMethodOnline {
+		services.WriteProblem(w, http.StatusConflict, "order is paid at the counter", nil)
+		return
+	}
+	if order.Status != "awaiting_payment" && order.Status != "scheduled" {
+		services.WriteProblem(w, http.StatusConflict, domain.ErrOrderNotPayable.Error(), nil)
+		return
+	}
+	paid, err := o.repo.OrderIsPaid(ctx, order.ID)
+	if err != nil {
+		o.logger.Error(orderNumber, "db_query_failed", "Database query failed", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot get the order payments: "+err.Error(), nil)
+		return
+	}
+	if paid {
+		services.WriteProblem(w, http.StatusConflict, domain.ErrOrderAlreadyPaid.Error(), nil)
+		return
+	}
+
+	// The charge is recorded as pending first, a charge the provider takes is never without a payments row
+	payment := domain.Payment{Provider: o.payments.Name(), Amount: order.TotalAmount}
+	if err := o.repo.StartCharge(ctx, &order, &payment); err != nil {
+		o.logger.Error(orderNumber, "db_query_failed", "Cannot record the pending payment", err, nil)
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot record the payment: "+err.Error(), nil)
+		return
+	}
+
+	ref, chargeErr := o.payments.Charge(ctx, order.Number, order.TotalAmount, req.Token)
+	payment.Status = "succeeded"
+	if chargeErr != nil {
+		msg := chargeErr.Error()
+		payment.Status = "failed"
+		payment.Error = &msg
+	} else {
+		payment.ProviderRef = &ref
+	}
+
+	err = o.repo.RecordCharge(ctx, &order, &payment)
+	switch {
+	case errors.Is(err, domain.ErrOrderNotPayable), errors.Is(err, domain.ErrOrderAlreadyPaid), errors.Is(err, domain.ErrOrderModified):
+		// The order changed while it was charged, the charge is refunded
+		o.logger.Info(orderNumber, "payment_refunded", "The order changed during the payment, the charge is refunded", map[string]interface{}{"payment_id": payment.ID, "reason": err.Error()})
+		o.sendRefunds(ctx, orderNumber)
+		services.WriteProblem(w, http.StatusConflict, err.Error()+", the payment is refunded", nil)
+		return
+	case err != nil:
+		o.logger.Error(orderNumber, "payment_record_failed", "Cannot record the payment", err, map[string]interface{}{"payment_id": payment.ID, "provider": payment.Provider, "provider_ref": ref, "payment_status": payment.Status})
+		// The payment row stays pending, money the provider took is given back right away
+		if chargeErr == nil {
+			o.refundUnrecorded(ctx, orderNumber, payment, ref)
+		}
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot record the payment: "+err.Error(), nil)
+		return
+	}
+
+	if errors.Is(chargeErr, domain.ErrPaymentDeclined) {
+		o.logger.Info(orderNumber, "payment_declined", "The payment is declined", map[string]interface{}{"payment_id": payment.ID, "provider": payment.Provider})
+		services.WriteProblem(w, http.StatusPaymentRequired, chargeErr.Error(), nil)
+		return
+	} else if chargeErr != nil {
+		o.logger.Error(orderNumber, "payment_failed", "The payment provider failed", chargeErr, map[string]interface{}{"payment_id": payment.ID, "provider": payment.Provider})
+		services.WriteProblem(w, http.StatusBadGateway, "the payment provider failed, please retry", nil)
+		return
+	}
+	o.logger.Info(orderNumber, "payment_captured", "The order is paid", map[string]interface{}{"payment_id": payment.ID, "provider": payment.Provider, "amount": payment.Amount.String()})
+
+	response := domain.PaymentResponse{
+		OrderNumber:   order.Number,
+		Status:        order.Status,
+		PaymentID:     payment.ID,
+		PaymentStatus: payment.Status,
+		Amount:        payment.Amount,
+		Provider:      payment.Provider,
+	}
+
+	// Paid orders were queued in the same transaction, scheduled ones wait for the scheduler
+	status := http.StatusOK
+	if err := o.publishOrderNow(ctx, order.ID); err != nil {
+		status = http.StatusAccepted
+		response.DispatchError = o.dispatchError(orderNumber, err)
+	}
+
+	services.WriteJSON(w, response, status)
+}
+
+// refundUnrecorded refunds a charge whose outcome could not be saved. The payment row stays pending
+// and the refund is only logged, the database is not reachable.
+func (o *OrderService) refundUnrecorded(ctx context.Context, orderNumber string, payment domain.Payment, chargeRef string) {
+	refundRef, err := o.payments.Refund(ctx, chargeRef, payment.Amount)
+	if err != nil {
+		o.logger.Error(orderNumber, "refund_failed", "The unrecorded charge cannot be refunded, it must be refunded by hand", err, map[string]interface{}{"payment_id": payment.ID, "charge_ref": chargeRef, "amount": payment.Amount.String()})
+		return
+	}
+	o.logger.Info(orderNumber, "refund_succeeded", "The unrecorded charge is refunded", map[string]interface{}{"payment_id": payment.ID, "charge_ref": chargeRef, "refund_ref": refundRef, "amount": payment.Amount.String()})
+}
+
+// sendRefunds sends the pending refunds of the order to the payment provider and records the answers.
+// Failed refunds are retried by RetryRefunds.
+func (o *OrderService) sendRefunds(ctx context.Context, orderNumber string) []domain.Payment {
+	pending, err := o.repo.ClaimRefunds(ctx, orderNumber, 0, refundBatchSize)
+	if err != nil {
+		o.logger.Error(orderNumber, "refund_claim_failed", "Cannot load the pending refunds", err, nil)
+		return nil
+	}
+	return o.refund(ctx, pending)
+}
+
+// RetryRefunds resends pending and failed refunds until ctx is cancelled
+func (o *OrderService) RetryRefunds(ctx context.Context) {
+	ticker := time.NewTicker(refundRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pending, err := o.repo.ClaimRefunds(ctx, "", refundRetryInterval, refundBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				o.logger.Error("", "refund_claim_failed", "Cannot load the pending refunds", err, nil)
+			}
+			continue
+		}
+		o.refund(ctx, pending)
+	}
+}
+
+func (o *OrderService) refund(ctx context.Context, pending []domain.PendingRefund) []domain.Payment {
+	refunds := make([]domain.Payment, 0, len(pending))
+	for _, p := range pending {
+		refund := p.Refund
+		ref, err := o.payments.Refund(ctx, p.ChargeRef, refund.Amount)
+		if err != nil {
+			o.logger.Error("", "refund_failed", "The payment provider did not accept the refund", err, map[string]interface{}{"payment_id": refund.ID, "order_id": refund.OrderID, "charge_ref": p.ChargeRef})
+		} else {
+			o.logger.Info("", "refund_succeeded", "The payment is refunded", map[string]interface{}{"payment_id": refund.ID, "order_id": refund.OrderID, "amount": refund.Amount.String()})
+		}
+		if err := o.repo.CompleteRefund(ctx, &refund, ref, err); err != nil {
+			o.logger.Error("", "refund_record_failed", "Cannot record the refund", err, map[string]interface{}{"payment_id": refund.ID, "provider_ref": ref})
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds
+}
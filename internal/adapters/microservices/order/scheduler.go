@@ -0,0 +1,39 @@
+package order
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	schedulerInterval  = time.Second
+	schedulerBatchSize = 50
+)
+
+// DispatchScheduledOrders sends due scheduled orders to the kitchen until ctx is cancelled
+func (o *OrderService) DispatchScheduledOrders(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		dispatched, err := o.repo.DispatchScheduledOrders(ctx, schedulerBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				o.logger.Error("", "scheduler_dispatch_failed", "Cannot dispatch scheduled orders", err, nil)
+			}
+			continue
+		}
+		for _, orderNumber := range dispatched {
+			o.logger.Info(orderNumber, "scheduled_order_dispatched", "Scheduled order is sent to the kitchen", nil)
+		}
+		if len(dispatched) > 0 {
+			o.wakeOutboxRelay()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wheres-my-pizza/internal/adapters/spool"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+const spoolDrainInterval = 5 * time.Second
+
+// errSpooled means the message is not published yet but safe in the disk spool
+var errSpooled = errors.New("message is spooled until rabbitmq is reachable")
+
+// spoolMessage appends the message to the disk spool. The outbox row is pushed back by the
+// maximum spool age, so the relay only retries it if the spool cannot deliver it in time.
+func (o *OrderService) spoolMessage(ctx context.Context, msg domain.OutboxMessage, cause error) error {
+	if err := o.spool.Append(msg); err != nil {
+		o.logger.Error("", "spool_append_failed", "Cannot spool the outbox message, it stays in the outbox", err, map[string]interface{}{"outbox_id": msg.ID})
+		return err
+	}
+	if err := o.repo.MarkOutboxFailed(ctx, msg.ID, o.spool.MaxAge(), cause); err != nil {
+		o.logger.Error("", "outbox_update_failed", "Cannot mark outbox message as spooled", err, map[string]interface{}{"outbox_id": msg.ID})
+	}
+	o.logSpoolStats("message_spooled", "The outbox message is spooled to disk", map[string]interface{}{"outbox_id": msg.ID, "routing_key": msg.RoutingKey})
+	return nil
+}
+
+// DrainSpool replays the spool after every RabbitMQ reconnect, and periodically in case
+// the connection stayed up while publishes timed out, until ctx is cancelled
+func (o *OrderService) DrainSpool(ctx context.Context) {
+	ticker := time.NewTicker(spoolDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.rabbit.Reconnected():
+		}
+		o.drainSpool(ctx)
+	}
+}
+
+func (o *OrderService) drainSpool(ctx context.Context) {
+	entries, quarantined, err := o.spool.Entries()
+	if err != nil {
+		o.logger.Error("", "spool_read_failed", "Cannot read the spool", err, nil)
+		return
+	}
+	// The outbox row of a quarantined message is still pending, the relay retries it once the spool max age is over
+	for _, name := range quarantined {
+		o.logger.Error("", "spool_file_quarantined", "Unreadable spool file is moved aside as .bad", errors.New("corrupt spool file"), map[string]interface{}{"file": name})
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		msg := entry.Message
+
+		// The outbox relay already took over messages older than the maximum age
+		if o.spool.Expired(entry, now) {
+			o.logger.Info("", "spool_expired", "Spooled message is too old, the outbox relay retries it", map[string]interface{}{"outbox_id": msg.ID, "spooled_at": entry.SpooledAt})
+			o.removeSpoolEntry(entry)
+			continue
+		}
+
+		err := o.rabbit.PublishOutboxMessage(ctx, msg)
+		if errors.Is(err, domain.ErrBrokerUnavailable) || errors.Is(err, domain.ErrPublishTimeout) {
+			// Still unreachable, keep the rest in order for the next attempt
+			o.logSpoolStats("spool_drain_paused", "RabbitMQ is still unreachable, the spool is kept", nil)
+			return
+		}
+		if errors.Is(err, domain.ErrPublishUnroutable) && msg.Exchange == "notifications_fanout" {
+			err = nil
+		}
+
+		if err != nil {
+			backoff := outboxBackoff(msg.Attempts + 1)
+			o.logger.Error("", "rabbitmq_publish_failed", "The spooled message was not accepted, the outbox relay retries it", err, map[string]interface{}{"outbox_id": msg.ID, "routing_key": msg.RoutingKey})
+			if err := o.repo.MarkOutboxFailed(ctx, msg.ID, backoff, err); err != nil {
+				o.logger.Error("", "outbox_update_failed", "Cannot mark outbox message as failed", err, map[string]interface{}{"outbox_id": msg.ID})
+			}
+		} else if err := o.repo.MarkOutboxSent(ctx, msg.ID); err != nil {
+			o.logger.Error("", "outbox_update_failed", "Cannot mark outbox message as sent", err, map[string]interface{}{"outbox_id": msg.ID})
+		}
+		o.removeSpoolEntry(entry)
+	}
+
+	o.logSpoolStats("spool_drained", "The spool is replayed to RabbitMQ", map[string]interface{}{"replayed": len(entries)})
+}
+
+func (o *OrderService) removeSpoolEntry(entry spool.Entry) {
+	if err := o.spool.Remove(entry); err != nil {
+		o.logger.Error("", "spool_remove_failed", "Cannot remove the spool entry", err, map[string]interface{}{"outbox_id": entry.Message.ID})
+	}
+}
+
+// logSpoolStats logs the action with the current spool size and age
+func (o *OrderService) logSpoolStats(action, msg string, extra map[string]interface{}) {
+	stats, err := o.spool.Stats()
+	if err != nil {
+		o.logger.Error("", "spool_read_failed", "Cannot read the spool", err, nil)
+		return
+	}
+	if extra == nil {
+		extra = map[string]interface{}{}
+	}
+	extra["spool_messages"] = stats.Messages
+	extra["spool_bytes"] = stats.Bytes
+	extra["spool_oldest_age_ms"] = stats.OldestAge.Milliseconds()
+	o.logger.Info("", action, msg, extra)
+}
@@ -0,0 +1,39 @@
+package order
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/services"
+)
+
+// POST /tables/{table_number}/close?location=DT
+func (o *OrderService) CloseTable(w http.ResponseWriter, r *http.Request) {
+	tableNumber, err := strconv.Atoi(r.PathValue("table_number"))
+	if err != nil || tableNumber < 1 || tableNumber > 100 {
+		services.WriteProblem(w, http.StatusBadRequest, "table number must be 1 - 100", nil)
+		return
+	}
+	locationID := r.URL.Query().Get("location")
+	if locationID == "" {
+		locationID = domain.DefaultLocation
+	}
+
+	bill, err := o.repo.CloseTableSession(r.Context(), locationID, tableNumber)
+	switch {
+	case errors.Is(err, domain.ErrTableNotOpen):
+		services.WriteProblem(w, http.StatusNotFound, err.Error(), nil)
+		return
+	case errors.Is(err, domain.ErrTableOrdersInProgress):
+		services.WriteProblem(w, http.StatusConflict, err.Error(), nil)
+		return
+	case err != nil:
+		o.logger.Error("", "db_transaction_failed", "The table session cannot be closed", err, map[string]interface{}{"location_id": locationID, "table_number": tableNumber})
+		services.WriteProblem(w, http.StatusInternalServerError, "Cannot close the table: "+err.Error(), nil)
+		return
+	}
+
+	o.logger.Info("", "table_closed", "The table session is closed", map[string]interface{}{"location_id": locationID, "table_number": tableNumber, "session_id": bill.SessionID, "orders": len(bill.Orders), "total_amount": bill.TotalAmount.String()})
+	services.WriteJSON(w, bill, http.StatusOK)
+}
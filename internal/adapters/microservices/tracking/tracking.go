@@ -2,11 +2,12 @@ package tracking
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 	"wheres-my-pizza/internal/adapters/db/repository"
+	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/internal/core/services"
 	"wheres-my-pizza/pkg/logger"
 
@@ -28,24 +29,18 @@ func (t *TrackingService) GetOrderDetails(w http.ResponseWriter, r *http.Request
 	t.logger.Info(orderNumber, "request_received", "Receiving anyAPI request", map[string]interface{}{"endpoint": r.URL.Path})
 	ctx := r.Context()
 
-	orderDetails, err := t.repo.GetOrderDetails(ctx, orderNumber)
+	// ?location=DT only finds orders of that restaurant
+	orderDetails, err := t.repo.GetOrderDetails(ctx, orderNumber, r.URL.Query().Get("location"))
 	if err == pgx.ErrNoRows {
-		http.Error(w, "order was not found", http.StatusNotFound)
+		services.WriteProblem(w, http.StatusNotFound, "order was not found", nil)
 		return
 	} else if err != nil {
 		t.logger.Error(orderNumber, "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
-		http.Error(w, "could not get order details from db: "+err.Error(), http.StatusInternalServerError)
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get order details from db: "+err.Error(), nil)
 		return
 	}
 
-	resp, err := json.Marshal(orderDetails)
-	if err != nil {
-		http.Error(w, "Cannot marshal the order", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(resp)
+	services.WriteJSON(w, orderDetails, http.StatusOK)
 }
 
 // GET /orders/{order_number}/history
@@ -56,31 +51,133 @@ func (t *TrackingService) GetOrderHistory(w http.ResponseWriter, r *http.Request
 	history, err := t.repo.GetOrderHistory(ctx, orderNumber)
 	if err != nil {
 		t.logger.Error(orderNumber, "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
-		http.Error(w, "could not get order history: "+err.Error(), http.StatusInternalServerError)
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get order history: "+err.Error(), nil)
 		return
 	}
 	if len(history) == 0 {
-		http.Error(w, "order not found", http.StatusNotFound)
+		services.WriteProblem(w, http.StatusNotFound, "order not found", nil)
 		return
 	}
 
 	services.WriteJSON(w, history, http.StatusOK)
 }
 
+// GET /orders?status=cooking&type=delivery&from=...&to=...&customer=...&worker=...&sort=priority&cursor=...
+func (t *TrackingService) SearchOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := services.ParseOrderSearch(r.URL.Query())
+	if err != nil {
+		services.WriteValidationProblem(w, err)
+		return
+	}
+
+	orders, err := t.repo.SearchOrders(ctx, filter)
+	if err != nil {
+		t.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not search orders: "+err.Error(), nil)
+		return
+	}
+
+	// One extra row is loaded to know whether there is a next page
+	response := domain.OrderSearchResponse{Orders: orders}
+	if len(orders) > filter.Limit {
+		response.Orders = orders[:filter.Limit]
+		cursor := services.EncodeOrderCursor(filter, response.Orders[filter.Limit-1])
+		response.NextCursor = &cursor
+	}
+	services.WriteJSON(w, response, http.StatusOK)
+}
+
+// GET /customers/{id}/orders?limit=20&offset=0
+func (t *TrackingService) GetCustomerOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	customerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || customerID < 1 {
+		services.WriteProblem(w, http.StatusBadRequest, "customer id must be a positive number", nil)
+		return
+	}
+
+	limit, offset, err := services.ParsePagination(r.URL.Query())
+	if err != nil {
+		services.WriteValidationProblem(w, err)
+		return
+	}
+
+	orders, total, err := t.repo.GetCustomerOrders(ctx, customerID, limit, offset)
+	if err == pgx.ErrNoRows {
+		services.WriteProblem(w, http.StatusNotFound, "customer was not found", nil)
+		return
+	} else if err != nil {
+		t.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get customer orders: "+err.Error(), nil)
+		return
+	}
+
+	response := domain.CustomerOrdersResponse{
+		CustomerID: customerID,
+		Orders:     orders,
+		Limit:      limit,
+		Offset:     offset,
+		Total:      total,
+	}
+	services.WriteJSON(w, response, http.StatusOK)
+}
+
 // GET /workers/status
 func (t *TrackingService) GetWorkersStatuses(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	workers, err := t.repo.GetWorkersStatuses(ctx, time.Duration(50))
+	// ?location=DT lists the workers of that restaurant only
+	workers, err := t.repo.GetWorkersStatuses(ctx, time.Duration(50), r.URL.Query().Get("location"))
 	if err != nil {
 		t.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
-		http.Error(w, "could not get workers statuses: "+err.Error(), http.StatusInternalServerError)
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get workers statuses: "+err.Error(), nil)
 		return
 	}
 
 	services.WriteJSON(w, workers, http.StatusOK)
 }
 
+// GET /couriers/status
+func (t *TrackingService) GetCouriersStatuses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// ?location=DT lists the couriers of that restaurant only
+	couriers, err := t.repo.GetCouriersStatuses(ctx, time.Duration(50), r.URL.Query().Get("location"))
+	if err != nil {
+		t.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get couriers statuses: "+err.Error(), nil)
+		return
+	}
+
+	services.WriteJSON(w, couriers, http.StatusOK)
+}
+
+// GET /tables/open?location=DT
+func (t *TrackingService) GetOpenTables(w http.ResponseWriter, r *http.Request) {
+	tables, err := t.repo.ListOpenTables(r.Context(), r.URL.Query().Get("location"))
+	if err != nil {
+		t.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get open tables: "+err.Error(), nil)
+		return
+	}
+
+	services.WriteJSON(w, tables, http.StatusOK)
+}
+
+// GET /inventory?location=DT
+func (t *TrackingService) GetInventory(w http.ResponseWriter, r *http.Request) {
+	inventory, err := t.repo.GetInventory(r.Context(), r.URL.Query().Get("location"))
+	if err != nil {
+		t.logger.Error("", "db_query_failed", "Database query failed", err, map[string]interface{}{"endpoint": r.URL.Path})
+		services.WriteProblem(w, http.StatusInternalServerError, "could not get inventory: "+err.Error(), nil)
+		return
+	}
+
+	services.WriteJSON(w, inventory, http.StatusOK)
+}
+
 func (o *TrackingService) Stop(ctx context.Context, server *http.Server) {
 	<-ctx.Done()
 	o.repo.Conn.Close()
@@ -0,0 +1,68 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/internal/core/ports"
+)
+
+// Tokens with a fixed outcome, every other token is approved
+const (
+	FakeTokenDeclined = "tok_declined"
+	FakeTokenError    = "tok_error"
+)
+
+// FakeProvider is an in-process payment provider for development.
+// Charges live in memory only, so refunds of charges from before a restart fail.
+type FakeProvider struct {
+	mu      sync.Mutex
+	seq     int
+	charges map[string]domain.Money // charge ref -> amount not refunded yet
+}
+
+var _ ports.PaymentProvider = (*FakeProvider)(nil)
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{charges: make(map[string]domain.Money)}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Charge(ctx context.Context, orderNumber string, amount domain.Money, token string) (string, error) {
+	switch token {
+	case FakeTokenDeclined:
+		return "", fmt.Errorf("%w: card declined", domain.ErrPaymentDeclined)
+	case FakeTokenError:
+		return "", fmt.Errorf("fake provider is unavailable")
+	}
+	if amount.Amount <= 0 {
+		return "", fmt.Errorf("%w: amount must be positive (got %s)", domain.ErrPaymentDeclined, amount)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	ref := fmt.Sprintf("fake_ch_%d", p.seq)
+	p.charges[ref] = amount
+	return ref, nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, chargeRef string, amount domain.Money) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	remaining, ok := p.charges[chargeRef]
+	if !ok {
+		return "", fmt.Errorf("charge %s is unknown", chargeRef)
+	}
+	if remaining.LessThan(amount) {
+		return "", fmt.Errorf("refund of %s exceeds the remaining %s of charge %s", amount, remaining, chargeRef)
+	}
+	p.charges[chargeRef] = remaining.Sub(amount)
+	p.seq++
+	return fmt.Sprintf("fake_re_%d", p.seq), nil
+}
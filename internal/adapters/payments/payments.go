@@ -0,0 +1,17 @@
+package payments
+
+import (
+	"fmt"
+	"wheres-my-pizza/internal/core/ports"
+	"wheres-my-pizza/pkg/config"
+)
+
+// New returns the payment provider selected in the payments config section
+func New(cfg config.PaymentsConfig) (ports.PaymentProvider, error) {
+	switch cfg.Provider {
+	case "", "fake":
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
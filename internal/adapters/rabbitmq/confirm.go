@@ -0,0 +1,83 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const confirmTimeout = 5 * time.Second
+
+var messageSeq atomic.Uint64
+
+// confirmPublisher publishes mandatory messages on a channel in confirm mode and waits
+// for the broker to ack them. Publishes are serialized so a basic.return belongs to the
+// message that is being confirmed.
+type confirmPublisher struct {
+	mu      sync.Mutex
+	ch      *amqp.Channel
+	returns chan amqp.Return
+	timeout time.Duration
+}
+
+func newConfirmPublisher(ch *amqp.Channel) (*confirmPublisher, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("cannot put the channel in confirm mode: %w", err)
+	}
+	return &confirmPublisher{
+		ch:      ch,
+		returns: ch.NotifyReturn(make(chan amqp.Return, 16)),
+		timeout: confirmTimeout,
+	}, nil
+}
+
+// publish returns nil only when the broker acked the message and routed it to a queue,
+// otherwise one of the domain.ErrPublish* errors or domain.ErrBrokerUnavailable
+func (p *confirmPublisher) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	if p == nil {
+		return domain.ErrBrokerUnavailable
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch.IsClosed() {
+		return domain.ErrBrokerUnavailable
+	}
+
+	msg.MessageId = strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(messageSeq.Add(1), 36)
+	confirm, err := p.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, msg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrBrokerUnavailable, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	if err != nil {
+		return fmt.Errorf("%w: exchange %s, routing key %s: %v", domain.ErrPublishTimeout, exchange, routingKey, err)
+	}
+	if !acked {
+		return fmt.Errorf("%w: exchange %s, routing key %s", domain.ErrPublishNacked, exchange, routingKey)
+	}
+
+	// The broker sends basic.return before the ack, returns of older timed out messages are skipped
+	for {
+		select {
+		case ret, ok := <-p.returns:
+			if !ok {
+				return nil
+			}
+			if ret.MessageId == msg.MessageId {
+				return fmt.Errorf("%w: exchange %s, routing key %s: %s", domain.ErrPublishUnroutable, exchange, routingKey, ret.ReplyText)
+			}
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/pkg/config"
+	"wheres-my-pizza/pkg/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type CourierRabbit struct {
+	Conn        *amqp.Connection
+	Ch          *amqp.Channel
+	DurationMs  time.Duration
+	publisher   atomic.Pointer[confirmPublisher] // replaced on reconnect while other goroutines publish
+	courierName string
+	location    string // only orders of this restaurant are delivered
+	logger      *logger.Logger
+	qos         int
+	url         string
+}
+
+func NewCourierRabbit(courierName, location string, qos int, logger *logger.Logger, cfg config.Config) (*CourierRabbit, error) {
+	rabbitURL := fmt.Sprintf("amqp://%s:%s@%s:%d/",
+		cfg.RabbitMQ.User, cfg.RabbitMQ.Password, cfg.RabbitMQ.Host,
+		cfg.RabbitMQ.Port)
+	rabbit := &CourierRabbit{qos: qos, logger: logger, courierName: courierName, location: location, url: rabbitURL}
+	if err := rabbit.connect(); err != nil {
+		return nil, err
+	}
+
+	// start reconnect watcher
+	go rabbit.handleReconnect(5 * time.Second)
+
+	return rabbit, nil
+}
+
+func (r *CourierRabbit) connect() error {
+	start := time.Now()
+
+	conn, err := amqp.Dial(r.url)
+	if err != nil {
+		return err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	// Couriers use the same exchanges as the kitchen
+	if err := setupKitchenChannel(ch, r.qos); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// Status updates are confirmed by the broker
+	publisher, err := newConfirmPublisher(ch)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	r.Conn = conn
+	r.Ch = ch
+	r.publisher.Store(publisher)
+	r.DurationMs = time.Duration(time.Since(start).Milliseconds())
+
+	r.logger.Info("rabbitmq", "connection_established", "Connected to RabbitMQ", map[string]interface{}{
+		"courier_name": r.courierName,
+	})
+	return nil
+}
+
+func (r *CourierRabbit) handleReconnect(backoff time.Duration) {
+	for {
+		reason, ok := <-r.Conn.NotifyClose(make(chan *amqp.Error))
+		if !ok {
+			fmt.Print("rabbitmq connection closed")
+			break
+		}
+		fmt.Printf("rabbitmq connection closed unexpectedly, reason: %v", reason)
+
+		for {
+			time.Sleep(backoff)
+			if err := r.connect(); err != nil {
+				fmt.Printf("Reconnect failed: %v\n", err)
+				continue
+			}
+
+			fmt.Println("Reconnect is succefull")
+			break
+		}
+	}
+}
+
+// declareDeliveryQueue declares delivery_{location}_queue bound to delivery.{location}.ready
+func declareDeliveryQueue(ch *amqp.Channel, location string, args amqp.Table) error {
+	queueName := "delivery_" + location + "_queue"
+	if _, err := ch.QueueDeclare(queueName, true, false, false, false, args); err != nil {
+		return err
+	}
+	return ch.QueueBind(queueName, "delivery."+location+".ready", "orders_topic", false, nil)
+}
+
+func (r *CourierRabbit) ConsumeMessages(ctx context.Context, errCh chan error) (chan domain.Order, error) {
+	args, err := declareDLQ(r.Ch)
+	if err != nil {
+		return nil, err
+	}
+	if err := declareDeliveryQueue(r.Ch, r.location, args); err != nil {
+		return nil, err
+	}
+
+	msgs, err := r.Ch.Consume(
+		"delivery_"+r.location+"_queue", // queue
+		"",                              // consumer tag
+		false,                           // auto-ack
+		false,                           // exclusive
+		false,                           // no-local
+		false,                           // no-wait
+		nil,                             // args
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	orderCh := make(chan domain.Order)
+	go r.handleMessages(msgs, orderCh, errCh)
+
+	return orderCh, nil
+}
+
+func (r *CourierRabbit) PublishStatusUpdateMessage(ctx context.Context, order domain.Order, oldOrderStatus string, seconds int) error {
+	t1 := time.Now()
+	t2 := t1.Add(time.Duration(seconds) * time.Second)
+	msg := domain.StatusUpdateMessage{OrderNumber: order.Number, OldStatus: oldOrderStatus, NewStatus: order.Status, ChangedBy: r.courierName, TimeStamp: t1, EstimatedCompletion: t2}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status update message: %w", err)
+	}
+
+	// Publish to exchange and wait for the confirm
+	err = r.publisher.Load().publish(ctx, "notifications_fanout", "", amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		DeliveryMode: amqp.Persistent, // make message persistent
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish status update message: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CourierRabbit) handleMessages(msgs <-chan amqp.Delivery, orderCh chan<- domain.Order, errCh <-chan error) {
+	for msg := range msgs {
+		order := domain.Order{}
+		if err := json.Unmarshal(msg.Body, &order); err != nil {
+			r.logger.Error("", "message_decode_failed", "Cannot decode the delivery message", err, map[string]interface{}{"courier_name": r.courierName})
+			msg.Nack(false, false) // dead-letter it, it will never decode
+			continue
+		}
+
+		r.logger.Debug(order.Number, "delivery_started", "Order is picked from the delivery queue", map[string]interface{}{"courier_name": r.courierName})
+
+		// After the delivery, acknowledge the message
+		orderCh <- order
+		if err := <-errCh; err != nil {
+			r.logger.Error(order.Number, "message_processing_failed", "Unrecoverable processing errors", err, map[string]interface{}{"courier_name": r.courierName})
+			msg.Nack(false, true)
+		} else {
+			r.logger.Debug(order.Number, "delivery_completed", "Order is fully delivered", map[string]interface{}{"courier_name": r.courierName})
+			msg.Ack(false)
+		}
+	}
+}
+
+func (r *CourierRabbit) Close() {
+	r.Ch.Close()
+	r.Conn.Close()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/pkg/config"
@@ -16,29 +17,25 @@ type KitchenRabbitInterface interface{}
 
 var _ KitchenRabbitInterface = (*KitchenRabbit)(nil)
 
-var (
-	dine_in    string   = "dine_in"
-	takeout    string   = "takeout"
-	delivery   string   = "delivery"
-	orderTypes []string = []string{dine_in, takeout, delivery}
-)
-
 type KitchenRabbit struct {
 	Conn       *amqp.Connection
 	Ch         *amqp.Channel
 	DurationMs time.Duration
+	publisher  atomic.Pointer[confirmPublisher] // replaced on reconnect while other goroutines publish
 	workerType []string
+	stations   []string // kitchen stations the worker cooks at
 	workerName string
+	location   string // only orders of this restaurant are consumed
 	logger     *logger.Logger
 	qos        int
 	url        string
 }
 
-func NewKitchenRabbit(workerType []string, workerName string, qos int, logger *logger.Logger, cfg config.Config) (*KitchenRabbit, error) {
+func NewKitchenRabbit(workerType, stations []string, workerName, location string, qos int, logger *logger.Logger, cfg config.Config) (*KitchenRabbit, error) {
 	rabbitURL := fmt.Sprintf("amqp://%s:%s@%s:%d/",
 		cfg.RabbitMQ.User, cfg.RabbitMQ.Password, cfg.RabbitMQ.Host,
 		cfg.RabbitMQ.Port)
-	rabbit := &KitchenRabbit{qos: qos, logger: logger, workerName: workerName, workerType: workerType, url: rabbitURL}
+	rabbit := &KitchenRabbit{qos: qos, logger: logger, workerName: workerName, location: location, workerType: workerType, stations: stations, url: rabbitURL}
 	if err := rabbit.connect(); err != nil {
 		return nil, err
 	}
@@ -66,8 +63,16 @@ func (r *KitchenRabbit) connect() error {
 		return err
 	}
 
+	// Status updates are confirmed by the broker
+	publisher, err := newConfirmPublisher(ch)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
 	r.Conn = conn
 	r.Ch = ch
+	r.publisher.Store(publisher)
 	r.DurationMs = time.Duration(time.Since(start).Milliseconds())
 
 	r.logger.Info("rabbitmq", "connection_established", "Connected to RabbitMQ", map[string]interface{}{
@@ -167,7 +172,12 @@ func setupKitchenChannel(ch *amqp.Channel, qos int) error {
 
 // Dead letter queue
 func (r *KitchenRabbit) dlq() (amqp.Table, error) {
-	_, err := r.Ch.QueueDeclare(
+	return declareDLQ(r.Ch)
+}
+
+// declareDLQ declares orders_dlq bound to orders_dlx and returns the args of queues that dead-letter to it
+func declareDLQ(ch *amqp.Channel) (amqp.Table, error) {
+	_, err := ch.QueueDeclare(
 		"orders_dlq", // DLQ name
 		true,         // durable
 		false,        // delete when unused
@@ -180,7 +190,7 @@ func (r *KitchenRabbit) dlq() (amqp.Table, error) {
 	}
 
 	// Bind DLQ to DLX
-	err = r.Ch.QueueBind(
+	err = ch.QueueBind(
 		"orders_dlq",
 		"#",          // catch all
 		"orders_dlx", // exchange
@@ -198,35 +208,31 @@ func (r *KitchenRabbit) dlq() (amqp.Table, error) {
 	return args, nil
 }
 
-func (r *KitchenRabbit) ConsumeMessages(ctx context.Context, workerName string, errCh chan error) (chan domain.Order, error) {
-	var queues []string
-	for _, orderType := range orderTypes {
-		queues = append(queues, "kitchen_"+orderType+"_queue")
-	}
-	queues = append(queues, "kitchen_queue")
-
+func (r *KitchenRabbit) ConsumeMessages(ctx context.Context, workerName string, errCh chan error) (chan domain.StationTicketMessage, error) {
 	args, err := r.dlq()
 	if err != nil {
 		return nil, err
 	}
 
-	// Queue declaring and binding
-	for i, queueName := range queues {
-		_, err := r.Ch.QueueDeclare(queueName, true, false, false, false, args)
-		if err != nil {
+	// Every station of a location has its own queue: kitchen_{location}_station_{station}_queue
+	var queues []string
+	for _, station := range r.stations {
+		queueName := "kitchen_" + r.location + "_station_" + station + "_queue"
+		if _, err := r.Ch.QueueDeclare(queueName, true, false, false, false, args); err != nil {
 			return nil, err
 		}
-		if queueName == "kitchen_queue" {
-			err = r.Ch.QueueBind(queues[i], "kitchen.*", "orders_topic", false, nil)
-		} else {
-			err = r.Ch.QueueBind(queues[i], "kitchen."+orderTypes[i]+".*", "orders_topic", false, nil)
-		}
-		if err != nil {
+		if err := r.Ch.QueueBind(queueName, "kitchen."+r.location+".station."+station, "orders_topic", false, nil); err != nil {
 			return nil, err
 		}
+		queues = append(queues, queueName)
 	}
 
-	orderCh := make(chan domain.Order)
+	// Ready delivery orders of the location wait in the courier queue even before a courier starts
+	if err := declareDeliveryQueue(r.Ch, r.location, args); err != nil {
+		return nil, err
+	}
+
+	ticketCh := make(chan domain.StationTicketMessage)
 	// Consuming messages
 	for _, queueName := range queues {
 		msgs, err := r.Ch.Consume(
@@ -242,10 +248,10 @@ func (r *KitchenRabbit) ConsumeMessages(ctx context.Context, workerName string,
 			return nil, err
 		}
 
-		go r.handleMessages(msgs, orderCh, errCh) // Start a goroutine for consuming messages from each queue
+		go r.handleMessages(msgs, ticketCh, errCh) // Start a goroutine for consuming messages from each queue
 	}
 
-	return orderCh, nil
+	return ticketCh, nil
 }
 
 func (r *KitchenRabbit) PublishStatusUpdateMessage(ctx context.Context, order domain.Order, oldOrderStatus, workerName string, seconds int) error {
@@ -258,44 +264,38 @@ func (r *KitchenRabbit) PublishStatusUpdateMessage(ctx context.Context, order do
 		return fmt.Errorf("failed to marshal order message: %w", err)
 	}
 
-	// Publish to exchange
-	err = r.Ch.PublishWithContext(
-		ctx,                    // context
-		"notifications_fanout", // exchange
-		"",                     // routing key
-		false,                  // mandatory
-		false,                  // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent, // make message persistent
-		},
-	)
+	// Publish to exchange and wait for the confirm
+	err = r.publisher.Load().publish(ctx, "notifications_fanout", "", amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		DeliveryMode: amqp.Persistent, // make message persistent
+	})
 	if err != nil {
-		return fmt.Errorf("failed to publish order message: %w", err)
+		return fmt.Errorf("failed to publish status update message: %w", err)
 	}
 
 	return nil
 }
 
-func (r *KitchenRabbit) handleMessages(msgs <-chan amqp.Delivery, orderCh chan<- domain.Order, errCh <-chan error) error {
+func (r *KitchenRabbit) handleMessages(msgs <-chan amqp.Delivery, ticketCh chan<- domain.StationTicketMessage, errCh <-chan error) error {
 	for msg := range msgs {
 
-		order := domain.Order{}
-		err := json.Unmarshal(msg.Body, &order)
+		ticket := domain.StationTicketMessage{}
+		err := json.Unmarshal(msg.Body, &ticket)
 		if err != nil {
 			return err
 		}
+		order := ticket.Order
 		// Check if the worker is specialized for this order type
 		if !r.isSpecializedForOrderType(order.Type) {
 			msg.Nack(false, true) // requeue the message
 			continue
 		}
 
-		r.logger.Debug(order.Number, "order_processing_started", "Order is picked from the queue", map[string]interface{}{"worker_name": r.workerName})
+		r.logger.Debug(order.Number, "order_processing_started", "Ticket is picked from the queue", map[string]interface{}{"worker_name": r.workerName, "station": ticket.Station})
 
 		// After processing, acknowledge the message
-		orderCh <- order
+		ticketCh <- ticket
 		err = <-errCh
 		if err != nil {
 			r.logger.Error(order.Number, "message_processing_failed", "Unrecoverable processing errors", err, map[string]interface{}{"worker_name": r.workerName})
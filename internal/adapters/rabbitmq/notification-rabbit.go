@@ -139,6 +139,15 @@ func (r *NotificationRabbit) ConsumeMessages(ctx context.Context) error {
 	for {
 		select {
 		case d := <-msgs:
+			// Low-stock events share the exchange with the status updates
+			var event struct {
+				Event string `json:"event"`
+			}
+			if err := json.Unmarshal(d.Body, &event); err == nil && event.Event == domain.LowStockEvent {
+				r.handleLowStock(d)
+				continue
+			}
+
 			var msg domain.StatusUpdateMessage
 
 			err := json.Unmarshal(d.Body, &msg)
@@ -159,6 +168,19 @@ func (r *NotificationRabbit) ConsumeMessages(ctx context.Context) error {
 	}
 }
 
+func (r *NotificationRabbit) handleLowStock(d amqp.Delivery) {
+	var msg domain.LowStockMessage
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		log.Printf("failed to unmarshal message: %v", err)
+		d.Nack(false, false) // reject, don’t requeue
+		return
+	}
+
+	r.logger.Info("", "low_stock_received", "Low-stock event is received", map[string]interface{}{"details": map[string]interface{}{"location_id": msg.LocationID, "ingredient_code": msg.IngredientCode, "quantity": msg.Quantity}})
+	fmt.Printf("Low stock at %s: %s has %d %s left (threshold %d %s).\n", msg.LocationID, msg.Name, msg.Quantity, msg.Unit, msg.LowStockThreshold, msg.Unit)
+	d.Ack(false)
+}
+
 func (r *NotificationRabbit) Close() {
 	if r.Ch != nil {
 		r.Ch.Close()
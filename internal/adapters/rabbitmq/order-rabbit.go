@@ -2,8 +2,8 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/pkg/config"
@@ -17,18 +17,20 @@ type OrderRabbitInterface interface{}
 var _ OrderRabbitInterface = (*OrderRabbit)(nil)
 
 type OrderRabbit struct {
-	Conn       *amqp.Connection
-	Ch         *amqp.Channel
-	DurationMs time.Duration
-	url        string
-	logger     *logger.Logger
+	Conn          *amqp.Connection
+	Ch            *amqp.Channel
+	DurationMs    time.Duration
+	publisher     atomic.Pointer[confirmPublisher] // replaced on reconnect while other goroutines publish
+	reconnectedCh chan struct{}
+	url           string
+	logger        *logger.Logger
 }
 
 func NewOrderRabbit(cfg config.Config) (*OrderRabbit, error) {
 	rabbitURL := fmt.Sprintf("amqp://%s:%s@%s:%d/",
 		cfg.RabbitMQ.User, cfg.RabbitMQ.Password, cfg.RabbitMQ.Host,
 		cfg.RabbitMQ.Port)
-	r := &OrderRabbit{url: rabbitURL}
+	r := &OrderRabbit{url: rabbitURL, reconnectedCh: make(chan struct{}, 1)}
 	if err := r.connect(); err != nil {
 		return nil, err
 	}
@@ -55,8 +57,16 @@ func (r *OrderRabbit) connect() error {
 		return err
 	}
 
+	// Every publish waits for the broker's ack and detects unroutable messages
+	publisher, err := newConfirmPublisher(ch)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
 	r.Conn = conn
 	r.Ch = ch
+	r.publisher.Store(publisher)
 	r.DurationMs = time.Since(start)
 
 	return nil
@@ -97,6 +107,10 @@ func (r *OrderRabbit) handleReconnect(backoff time.Duration) {
 			}
 
 			fmt.Println("Reconnect is succefull")
+			select {
+			case r.reconnectedCh <- struct{}{}:
+			default:
+			}
 			break
 		}
 
@@ -126,34 +140,24 @@ func (r *OrderRabbit) handleReconnect(backoff time.Duration) {
 	// }
 }
 
-func (r *OrderRabbit) PublishOrderMessage(ctx context.Context, order domain.Order) error {
-	body, err := json.Marshal(order)
-	if err != nil {
-		return fmt.Errorf("failed to marshal order message: %w", err)
-	}
+// Reconnected signals every successful reconnect, e.g. to replay the spool
+func (r *OrderRabbit) Reconnected() <-chan struct{} {
+	return r.reconnectedCh
+}
 
-	// Create routing key: kitchen.{order_type}.{priority}
-	routingKey := fmt.Sprintf("kitchen.%s.%d", order.Type, order.Priority)
-
-	// Publish to exchange
-	err = r.Ch.PublishWithContext(
-		ctx,            // context
-		"orders_topic", // exchange
-		routingKey,     // routing key
-		false,          // mandatory
-		false,          // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         body,
-			DeliveryMode: amqp.Persistent, // make message persistent
-			Priority:     uint8(order.Priority),
-		},
-	)
-	if err != nil {
-		return err
-	}
+func (r *OrderRabbit) PublishOutboxMessage(ctx context.Context, msg domain.OutboxMessage) error {
+	return r.publish(ctx, msg.Exchange, msg.RoutingKey, msg.Payload, msg.Priority)
+}
 
-	return nil
+// publish sends a persistent mandatory message and waits for the publisher confirm,
+// failures are domain.ErrPublish* or domain.ErrBrokerUnavailable
+func (r *OrderRabbit) publish(ctx context.Context, exchange, routingKey string, body []byte, priority int) error {
+	return r.publisher.Load().publish(ctx, exchange, routingKey, amqp.Publishing{
+		ContentType:  "application/json",
+		Body:         body,
+		DeliveryMode: amqp.Persistent, // make message persistent
+		Priority:     uint8(priority),
+	})
 }
 
 func (r *OrderRabbit) Close() {
@@ -0,0 +1,212 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/pkg/config"
+)
+
+var ErrSpoolFull = errors.New("spool is full")
+
+// Spool is an append-only directory of outbox messages that could not be published.
+// Every message is one JSON file named by a sequence number, so replaying the files
+// in name order keeps the publishing order.
+type Spool struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	nextSeq  uint64
+}
+
+// Entry is a spooled message, Name identifies its file
+type Entry struct {
+	Name      string
+	SpooledAt time.Time
+	Message   domain.OutboxMessage
+}
+
+// Stats describes the spool for logs
+type Stats struct {
+	Messages  int
+	Bytes     int64
+	OldestAge time.Duration
+}
+
+type entryFile struct {
+	SpooledAt time.Time            `json:"spooled_at"`
+	Message   domain.OutboxMessage `json:"message"`
+}
+
+func New(cfg config.SpoolConfig) (*Spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("cannot create spool directory: %w", err)
+	}
+	s := &Spool{
+		dir:      cfg.Dir,
+		maxBytes: int64(cfg.MaxMB) << 20,
+		maxAge:   time.Duration(cfg.MaxAgeMinutes) * time.Minute,
+	}
+
+	// Continue numbering after the files left by the previous run
+	names, err := s.names()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		fmt.Sscanf(names[len(names)-1], "%020d.json", &s.nextSeq)
+	}
+	s.nextSeq++
+	return s, nil
+}
+
+// Append stores the message after every message already in the spool.
+// The file is written under a temporary name and renamed, so a crash never leaves half a message.
+func (s *Spool) Append(msg domain.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(entryFile{SpooledAt: time.Now().UTC(), Message: msg})
+	if err != nil {
+		return err
+	}
+	stats, err := s.stats()
+	if err != nil {
+		return err
+	}
+	if stats.Bytes+int64(len(data)) > s.maxBytes {
+		return fmt.Errorf("%w: %d bytes in %d messages", ErrSpoolFull, stats.Bytes, stats.Messages)
+	}
+
+	name := fmt.Sprintf("%020d.json", s.nextSeq)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.nextSeq++
+	return nil
+}
+
+// Entries returns the spooled messages in the order they were appended. Files that cannot be read
+// or decoded are renamed to *.bad so they do not block the spool, their names are returned as quarantined.
+func (s *Spool) Entries() ([]Entry, []string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.names()
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]Entry, 0, len(names))
+	var quarantined []string
+	for _, name := range names {
+		path := filepath.Join(s.dir, name)
+		data, err := os.ReadFile(path)
+		var file entryFile
+		if err == nil {
+			err = json.Unmarshal(data, &file)
+		}
+		if err != nil {
+			if err := os.Rename(path, path+".bad"); err != nil {
+				return nil, nil, fmt.Errorf("cannot quarantine spool file %s: %w", name, err)
+			}
+			quarantined = append(quarantined, name)
+			continue
+		}
+		entries = append(entries, Entry{Name: name, SpooledAt: file.SpooledAt, Message: file.Message})
+	}
+	return entries, quarantined, nil
+}
+
+// Remove deletes a replayed or expired entry
+func (s *Spool) Remove(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.Remove(filepath.Join(s.dir, entry.Name))
+}
+
+// Expired reports whether the entry is older than the configured maximum age
+func (s *Spool) Expired(entry Entry, now time.Time) bool {
+	return now.Sub(entry.SpooledAt) > s.maxAge
+}
+
+func (s *Spool) MaxAge() time.Duration {
+	return s.maxAge
+}
+
+func (s *Spool) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names, err := s.names()
+	return err == nil && len(names) == 0
+}
+
+func (s *Spool) Stats() (Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats()
+}
+
+func (s *Spool) stats() (Stats, error) {
+	names, err := s.names()
+	if err != nil {
+		return Stats{}, err
+	}
+	var stats Stats
+	now := time.Now()
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(s.dir, name))
+		if err != nil {
+			return Stats{}, err
+		}
+		stats.Messages++
+		stats.Bytes += info.Size()
+		if age := now.Sub(info.ModTime()); age > stats.OldestAge {
+			stats.OldestAge = age
+		}
+	}
+	return stats, nil
+}
+
+// names lists the message files sorted by sequence number, temporary files are skipped
+func (s *Spool) names() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() && strings.HasSuffix(dirEntry.Name(), ".json") {
+			names = append(names, dirEntry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
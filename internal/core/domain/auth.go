@@ -0,0 +1,24 @@
+package domain
+
+import "errors"
+
+// Scopes of API keys and bearer tokens
+const (
+	ScopeOrdersWrite = "orders:write"
+	ScopeOrdersRead  = "orders:read"
+	ScopeWorkersRead = "workers:read"
+)
+
+var (
+	ErrMissingCredentials = errors.New("an X-API-Key header or a bearer token is required")
+	ErrInvalidAPIKey      = errors.New("the API key is unknown or revoked")
+	ErrInvalidToken       = errors.New("the bearer token is invalid")
+	ErrTokenExpired       = errors.New("the bearer token has expired")
+)
+
+// Principal is the caller of a request, authenticated by an API key or a bearer token
+type Principal struct {
+	Subject string // API key name or token sub claim
+	Method  string // api_key or jwt
+	Scopes  []string
+}
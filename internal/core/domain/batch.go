@@ -0,0 +1,25 @@
+package domain
+
+import "errors"
+
+var ErrBatchRolledBack = errors.New("batch was rolled back, no order was saved")
+
+const (
+	BatchModeAllOrNothing = "all_or_nothing"
+	BatchModeBestEffort   = "best_effort"
+)
+
+type BatchOrderResult struct {
+	Index       int          `json:"index"`
+	OrderNumber string       `json:"order_number,omitempty"`
+	Status      string       `json:"status"` // received, rejected or not_committed
+	TotalAmount *Money       `json:"total_amount,omitempty"`
+	Errors      []FieldError `json:"errors,omitempty"`
+}
+
+type BatchOrderResponse struct {
+	Mode      string             `json:"mode"`
+	Committed int                `json:"committed"`
+	Rejected  int                `json:"rejected"`
+	Results   []BatchOrderResult `json:"results"`
+}
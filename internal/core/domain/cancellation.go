@@ -0,0 +1,22 @@
+package domain
+
+import "errors"
+
+var (
+	ErrOrderNotCancellable   = errors.New("order cannot be cancelled in its current status")
+	ErrOrderCookingNoManager = errors.New("order is already cooking, cancelling it requires manager_override")
+	ErrOrderCancelled        = errors.New("order was cancelled")
+)
+
+type CancelOrderRequest struct {
+	Reason          string `json:"reason"`
+	ManagerOverride bool   `json:"manager_override"` // required to cancel an order that is already cooking
+}
+
+type CancelOrderResponse struct {
+	OrderNumber    string    `json:"order_number"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	Reason         string    `json:"reason"`
+	Refunds        []Payment `json:"refunds,omitempty"` // refunds of the order's payments
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrCustomerExists = errors.New("a customer with this phone or email already exists")
+
+// Customer is a registered customer, recognized by phone or email
+type Customer struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Phone     *string   `json:"phone"` // nullable, unique
+	Email     *string   `json:"email"` // nullable, unique
+}
+
+// CustomerOrder is one entry of a customer's order history
+type CustomerOrder struct {
+	OrderNumber string    `json:"order_number"`
+	CreatedAt   time.Time `json:"created_at"`
+	Type        string    `json:"order_type"`
+	Status      string    `json:"status"`
+	TotalAmount Money     `json:"total_amount"`
+}
+
+type CustomerOrdersResponse struct {
+	CustomerID int             `json:"customer_id"`
+	Orders     []CustomerOrder `json:"orders"`
+	Limit      int             `json:"limit"`
+	Offset     int             `json:"offset"`
+	Total      int             `json:"total"`
+}
@@ -0,0 +1,5 @@
+package domain
+
+import "errors"
+
+var ErrOrderDelivered = errors.New("order was already delivered")
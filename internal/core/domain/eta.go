@@ -0,0 +1,9 @@
+package domain
+
+// KitchenLoad is the state of the kitchen an ETA is estimated from
+type KitchenLoad struct {
+	Ahead       int     // waiting orders of the same type and location that are cooked first
+	Cooking     int     // orders of the same type and location that are cooking
+	Workers     int     // online workers of the location that cook the type
+	CookSeconds float64 // average cook duration of the type, from order_status_log
+}
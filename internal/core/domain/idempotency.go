@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request body")
+
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+	Response    *PutOrderResponse // filled by the repository with the stored or new response
+	Replayed    bool              // true if the response comes from an earlier request
+}
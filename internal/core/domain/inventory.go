@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+const LowStockEvent = "low_stock"
+
+// RecipeLine is the quantity of one ingredient used by one unit of a menu item
+type RecipeLine struct {
+	SKU            string `json:"sku"`
+	IngredientCode string `json:"ingredient_code"`
+	Quantity       int64  `json:"quantity"`
+}
+
+// StockNeed is the quantity of one ingredient an order takes, ItemIndex is the first item that uses it
+type StockNeed struct {
+	IngredientCode string
+	Quantity       int64
+	ItemIndex      int
+}
+
+type InventoryItem struct {
+	LocationID        string    `json:"location_id"`
+	IngredientCode    string    `json:"ingredient_code"`
+	Name              string    `json:"name"`
+	Unit              string    `json:"unit"` // g, ml, pcs
+	Quantity          int64     `json:"quantity"`
+	LowStockThreshold int64     `json:"low_stock_threshold"`
+	LowStock          bool      `json:"low_stock"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// LowStockMessage is published on notifications_fanout when the stock of an ingredient falls below its threshold
+type LowStockMessage struct {
+	Event             string    `json:"event"` // low_stock
+	LocationID        string    `json:"location_id"`
+	IngredientCode    string    `json:"ingredient_code"`
+	Name              string    `json:"name"`
+	Unit              string    `json:"unit"`
+	Quantity          int64     `json:"quantity"`
+	LowStockThreshold int64     `json:"low_stock_threshold"`
+	OrderNumber       string    `json:"order_number"`
+	TimeStamp         time.Time `json:"timestamp"`
+}
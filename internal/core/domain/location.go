@@ -0,0 +1,4 @@
+package domain
+
+// DefaultLocation is used for orders and workers that do not name a location
+const DefaultLocation = "MAIN"
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+type MenuItem struct {
+	SKU       string    `json:"sku"`
+	Name      string    `json:"name"`
+	Price     Money     `json:"price"`
+	Available bool      `json:"available"`
+	Station   string    `json:"station"` // oven, cold, fryer
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MenuModifier changes a menu item, PriceDelta is added to the item price and may be negative
+type MenuModifier struct {
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Kind       string    `json:"kind"` // size, topping, extra, removal
+	PriceDelta Money     `json:"price_delta"`
+	Available  bool      `json:"available"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -8,6 +8,8 @@ type OrderDetailsResponse struct {
 	UpdatedAt           time.Time  `json:"updated_at"`
 	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
 	ProcessedBy         string     `json:"processed_by"`
+	ScheduledFor        *time.Time `json:"scheduled_for,omitempty"`
+	LocationID          string     `json:"location_id"`
 }
 
 type StatusUpdateMessage struct {
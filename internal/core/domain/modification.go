@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrOrderNotModifiable = errors.New("order can only be modified while it is scheduled, awaiting_payment or received")
+	ErrOrderModified      = errors.New("order was modified concurrently, please retry")
+	ErrStaleOrderMessage  = errors.New("order message is older than the current order version")
+)
+
+type ModifyOrderRequest struct {
+	Add    []OrderItem       `json:"add"`    // new items, resolved by SKU; an SKU already in the order is added again with other modifiers
+	Update []OrderItemChange `json:"update"` // quantity or modifier changes of existing items
+	Remove []OrderItemRef    `json:"remove"` // items to remove
+}
+
+// OrderItemRef references an item of the order by its id, or by SKU or name. When the order holds the SKU
+// more than once, Modifiers (the codes of the item's modifiers) picks the item, an ambiguous reference is rejected.
+type OrderItemRef struct {
+	ItemID    int      `json:"item_id"`
+	Name      string   `json:"name"`      // SKU or name of the item
+	Modifiers []string `json:"modifiers"` // optional, nil matches any modifiers
+}
+
+// UnmarshalJSON accepts a reference object, an item id (12) or an SKU or name ("PIZZA-MARG")
+func (r *OrderItemRef) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.ItemID); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &r.Name); err == nil {
+		return nil
+	}
+	type ref OrderItemRef // without this method
+	return json.Unmarshal(data, (*ref)(r))
+}
+
+// OrderItemChange references an item like OrderItemRef and changes its quantity or modifiers
+type OrderItemChange struct {
+	ItemID       int                  `json:"item_id"`
+	Name         string               `json:"name"`
+	Modifiers    []string             `json:"modifiers"`
+	Quantity     *int                 `json:"quantity"`      // nil keeps the quantity
+	NewModifiers *[]OrderItemModifier `json:"new_modifiers"` // replaces the modifiers when set, resolved by code
+}
+
+func (c OrderItemChange) Ref() OrderItemRef {
+	return OrderItemRef{ItemID: c.ItemID, Name: c.Name, Modifiers: c.Modifiers}
+}
@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const DefaultCurrency = "USD"
+
+// Money is an exact amount in minor units (cents) of a currency.
+// In JSON and SQL it is a decimal with two places, e.g. 15.99, so the API stays compatible
+// with the former float64 fields and decimal(10,2) columns.
+type Money struct {
+	Amount   int64  // minor units
+	Currency string // ISO 4217 code, empty means DefaultCurrency
+}
+
+func NewMoney(minorUnits int64) Money {
+	return Money{Amount: minorUnits, Currency: DefaultCurrency}
+}
+
+// ParseMoney parses a decimal like "15.99", "-2.5" or "10" without going through float64
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("invalid money value %q", value)
+	}
+	if len(fraction) > 2 {
+		// More precision than cents is only accepted if it is zeros, e.g. 15.990
+		if strings.Trim(fraction[2:], "0") != "" {
+			return Money{}, fmt.Errorf("invalid money value %q: more than 2 decimal places", value)
+		}
+		fraction = fraction[:2]
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || strings.HasPrefix(whole, "+") || strings.HasPrefix(whole, "-") {
+		return Money{}, fmt.Errorf("invalid money value %q", value)
+	}
+	cents, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil || strings.HasPrefix(fraction, "+") || strings.HasPrefix(fraction, "-") {
+		return Money{}, fmt.Errorf("invalid money value %q", value)
+	}
+	if units > (math.MaxInt64-cents)/100 {
+		return Money{}, fmt.Errorf("invalid money value %q: out of range", value)
+	}
+
+	amount := units*100 + cents
+	if negative {
+		amount = -amount
+	}
+	return NewMoney(amount), nil
+}
+
+// String formats the amount as a decimal with two places
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: m.currency()}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.currency()}
+}
+
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.currency()}
+}
+
+// Percent returns percent of the amount rounded half away from zero to the minor unit
+func (m Money) Percent(percent float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * percent / 100)), Currency: m.currency()}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.Amount < other.Amount
+}
+
+func MinMoney(a, b Money) Money {
+	if a.Amount < b.Amount {
+		return a
+	}
+	return b
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number (15.99) or a string ("15.99")
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+	parsed, err := ParseMoney(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads numeric columns, pgx passes them as their text representation
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case []byte:
+		return m.Scan(string(v))
+	case int64:
+		*m = NewMoney(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+// Value writes the amount as a decimal string so numeric columns get the exact value
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,55 @@
+package domain
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "15.99", want: 1599},
+		{value: "10", want: 1000},
+		{value: "-2.5", want: -250},
+		{value: "+3.05", want: 305},
+		{value: ".5", want: 50},
+		{value: "7.", want: 700},
+		{value: "15.990", want: 1599},
+		{value: " 1.10 ", want: 110},
+		{value: "15.999", wantErr: true},
+		{value: "", wantErr: true},
+		{value: ".", wantErr: true},
+		{value: "--5", wantErr: true},
+		{value: "1.+5", wantErr: true},
+		{value: "1.-5", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "1e3", wantErr: true},
+		{value: "92233720368547758.08", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseMoney(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %s, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) error = %v", tt.value, err)
+			}
+			if got.Amount != tt.want || got.Currency != DefaultCurrency {
+				t.Errorf("ParseMoney(%q) = %+v, want %d minor units", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for amount, want := range map[int64]string{1599: "15.99", 5: "0.05", -250: "-2.50", 0: "0.00"} {
+		if got := NewMoney(amount).String(); got != want {
+			t.Errorf("NewMoney(%d).String() = %s, want %s", amount, got, want)
+		}
+	}
+}
@@ -3,33 +3,76 @@ package domain
 import "time"
 
 type Order struct {
-	ID              int         `json:"id"` // serial
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
-	Number          string      `json:"number"` // i dont know what it is
-	CustomerName    string      `json:"customer_name"`
-	Type            string      `json:"order_type"`       // dine_in, takeout, delivery
-	TableNumber     *int        `json:"table_number"`     // nullable
-	DeliveryAddress *string     `json:"delivery_address"` // nullable
-	TotalAmount     float64     `json:"total_amount"`
-	Priority        int         `json:"priority"`
-	Status          string      `json:"status"`
-	ProcessedBy     *string     `json:"processed_by"` // nullable
-	CompletedAt     *time.Time  `json:"completed_at"` // nullable
-	Items           []OrderItem `json:"items"`        // assumed sub-struct
+	ID               int         `json:"id"` // serial
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+	Number           string      `json:"number"` // i dont know what it is
+	CustomerName     string      `json:"customer_name"`
+	Type             string      `json:"order_type"`       // dine_in, takeout, delivery
+	TableNumber      *int        `json:"table_number"`     // nullable
+	DeliveryAddress  *string     `json:"delivery_address"` // nullable
+	TotalAmount      Money       `json:"total_amount"`
+	Priority         int         `json:"priority"`
+	PriorityReasons  []string    `json:"priority_reasons"` // why the priority policy chose Priority
+	Status           string      `json:"status"`
+	ProcessedBy      *string     `json:"processed_by"`                 // nullable
+	CompletedAt      *time.Time  `json:"completed_at"`                 // nullable
+	Items            []OrderItem `json:"items"`                        // assumed sub-struct
+	Version          int         `json:"version"`                      // incremented on every modification
+	ScheduledFor     *time.Time  `json:"scheduled_for,omitempty"`      // nullable, pickup time of a scheduled order
+	PromoCode        *string     `json:"promo_code,omitempty"`         // nullable
+	DiscountAmount   Money       `json:"discount_amount"`              // already subtracted from TotalAmount
+	CustomerID       *int        `json:"customer_id,omitempty"`        // nullable, registered customer
+	Customer         *Customer   `json:"customer,omitempty"`           // request only: found by phone/email or created
+	LocationID       string      `json:"location_id"`                  // restaurant, DefaultLocation when empty
+	PaymentMethod    string      `json:"payment_method"`               // counter (default) or online
+	TableSessionID   *int        `json:"table_session_id,omitempty"`   // nullable, tab of a dine_in order
+	EstimatedReadyAt *time.Time  `json:"estimated_ready_at,omitempty"` // nullable, ETA estimated when the order was placed or modified
 }
 
 type OrderItem struct {
-	ID        int       `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	OrderID   int       `json:"order_id"`
-	Name      string    `json:"name"`
-	Quantity  int       `json:"quantity"`
-	Price     float64   `json:"price"`
+	ID        int                 `json:"id"`
+	CreatedAt time.Time           `json:"created_at"`
+	OrderID   int                 `json:"order_id"`
+	SKU       string              `json:"sku"` // menu item, name and price are taken from the catalog
+	Name      string              `json:"name"`
+	Quantity  int                 `json:"quantity"`
+	Price     Money               `json:"price"`   // base price, without modifiers
+	Station   string              `json:"station"` // kitchen station, taken from the catalog
+	Modifiers []OrderItemModifier `json:"modifiers,omitempty"`
+	Stored    bool                `json:"-"` // loaded from order_items and already priced, never set by a client
+}
+
+// OrderItemModifier is a size, topping, extra or removal chosen for one item
+type OrderItemModifier struct {
+	ID          int    `json:"id"`
+	OrderItemID int    `json:"order_item_id"`
+	Code        string `json:"code"` // menu modifier, name and price delta are taken from the catalog
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	PriceDelta  Money  `json:"price_delta"`
+}
+
+// UnitPrice is the price of one unit with its modifiers
+func (i OrderItem) UnitPrice() Money {
+	price := i.Price
+	for _, modifier := range i.Modifiers {
+		price = price.Add(modifier.PriceDelta)
+	}
+	return price
 }
 
 type PutOrderResponse struct {
-	OrderNumber string  `json:"order_number"`
-	Status      string  `json:"status"`
-	TotalAmount float64 `json:"total_amount"`
+	OrderNumber      string     `json:"order_number"`
+	Status           string     `json:"status"`
+	TotalAmount      Money      `json:"total_amount"`
+	ScheduledFor     *time.Time `json:"scheduled_for,omitempty"`
+	PromoCode        *string    `json:"promo_code,omitempty"`
+	DiscountAmount   Money      `json:"discount_amount"`
+	CustomerID       *int       `json:"customer_id,omitempty"`
+	LocationID       string     `json:"location_id"`
+	PaymentMethod    string     `json:"payment_method"`
+	TableSessionID   *int       `json:"table_session_id,omitempty"`
+	EstimatedReadyAt *time.Time `json:"estimated_ready_at,omitempty"`
+	DispatchError    string     `json:"dispatch_error,omitempty"` // the order is saved but not yet in the kitchen queue
 }
@@ -0,0 +1,11 @@
+package domain
+
+type OutboxMessage struct {
+	ID         int
+	OrderID    int
+	Exchange   string
+	RoutingKey string
+	Payload    []byte
+	Priority   int
+	Attempts   int
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Payment methods of an order
+const (
+	PaymentMethodCounter = "counter" // paid at the counter, the order goes to the kitchen right away
+	PaymentMethodOnline  = "online"  // paid through the payment provider before the kitchen gets it
+)
+
+var (
+	ErrPaymentDeclined  = errors.New("payment was declined")
+	ErrOrderNotPayable  = errors.New("order is not awaiting payment")
+	ErrOrderAlreadyPaid = errors.New("order is already paid")
+)
+
+// Payment is a charge or a refund of an order at the payment provider
+type Payment struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	OrderID     int       `json:"order_id"`
+	Kind        string    `json:"kind"` // charge, refund
+	Provider    string    `json:"provider"`
+	ProviderRef *string   `json:"provider_ref"` // nullable, id of the charge or refund at the provider
+	RefundOf    *int      `json:"refund_of,omitempty"`
+	Amount      Money     `json:"amount"`
+	Status      string    `json:"status"` // pending, succeeded, failed
+	Error       *string   `json:"error,omitempty"`
+}
+
+type PaymentRequest struct {
+	Token  string `json:"token"`  // card token from the provider's client library
+	Amount *Money `json:"amount"` // optional, must equal the order total
+}
+
+type PaymentResponse struct {
+	OrderNumber   string `json:"order_number"`
+	Status        string `json:"status"` // order status
+	PaymentID     int    `json:"payment_id"`
+	PaymentStatus string `json:"payment_status"`
+	Amount        Money  `json:"amount"`
+	Provider      string `json:"provider"`
+	DispatchError string `json:"dispatch_error,omitempty"` // the order is paid but not yet in the kitchen queue
+}
+
+// PendingRefund is a refund still to be sent to the provider for the charge ChargeRef
+type PendingRefund struct {
+	Refund    Payment
+	ChargeRef string
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "strings"
+
+// FieldError describes one invalid field, Field is a JSON path like items[2].quantity
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every violation found in a request
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fieldErr := range v {
+		messages = append(messages, fieldErr.Field+": "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Problem is the JSON error body (RFC 7807) returned by every HTTP endpoint
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Validation error codes
+const (
+	CodeRequired    = "required"
+	CodeInvalid     = "invalid"
+	CodeOutOfRange  = "out_of_range"
+	CodeNotAllowed  = "not_allowed"
+	CodeUnknown     = "unknown"
+	CodeUnavailable = "unavailable"
+	CodeDuplicate   = "duplicate"
+	CodeOutOfStock  = "out_of_stock"
+)
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+type Promotion struct {
+	Code           string
+	Description    string
+	RuleType       string   // percent_off, amount_off, bundle_price
+	Percent        *float64 // percent_off
+	Amount         *Money   // amount_off: discount, bundle_price: price of one bundle
+	SKUPrefix      *string  // bundle_price: items that form a bundle
+	BundleQuantity *int     // bundle_price: units in one bundle
+	OrderType      *string  // only orders of this type
+	Weekday        *int     // only on this weekday, 0 = Sunday
+	MinTotal       Money
+	ValidFrom      time.Time
+	ValidUntil     *time.Time
+	MaxUses        *int
+	Uses           int
+	Active         bool
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "errors"
+
+// Errors of a confirmed publish to RabbitMQ, wrapped with the exchange and routing key
+var (
+	ErrBrokerUnavailable = errors.New("rabbitmq channel is not open")
+	ErrPublishNacked     = errors.New("rabbitmq rejected the message")
+	ErrPublishUnroutable = errors.New("rabbitmq could not route the message to any queue")
+	ErrPublishTimeout    = errors.New("rabbitmq did not confirm the message in time")
+)
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// OrderSearchFilter selects orders for GET /orders, empty fields match everything
+type OrderSearchFilter struct {
+	Statuses     []string
+	Type         string
+	From         *time.Time // created_at >= From
+	To           *time.Time // created_at < To
+	CustomerID   *int
+	CustomerName string // case-insensitive prefix
+	Worker       string
+	LocationID   string
+	SortBy       string // created_at or priority
+	Desc         bool
+	Limit        int
+	After        *OrderCursor // keyset position, the page starts after this order
+}
+
+// OrderCursor is the sort key of the last order of a page and the sort it was taken from
+type OrderCursor struct {
+	SortBy    string    `json:"s"`
+	Desc      bool      `json:"d"`
+	CreatedAt time.Time `json:"c"`
+	Priority  int       `json:"p"`
+	ID        int       `json:"i"`
+}
+
+// OrderSummary is one order in search results
+type OrderSummary struct {
+	ID           int        `json:"-"`
+	OrderNumber  string     `json:"order_number"`
+	CustomerName string     `json:"customer_name"`
+	CustomerID   *int       `json:"customer_id,omitempty"`
+	Type         string     `json:"order_type"`
+	Status       string     `json:"status"`
+	Priority     int        `json:"priority"`
+	TotalAmount  Money      `json:"total_amount"`
+	ProcessedBy  *string    `json:"processed_by"`
+	LocationID   string     `json:"location_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+}
+
+type OrderSearchResponse struct {
+	Orders     []OrderSummary `json:"orders"`
+	NextCursor *string        `json:"next_cursor"` // null on the last page
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "errors"
+
+// Stations of the kitchen, every menu item is cooked at one of them
+var Stations = []string{"oven", "cold", "fryer"}
+
+var ErrTicketDone = errors.New("station ticket is already done")
+
+// StationTicketMessage is the part of an order cooked at one station
+type StationTicketMessage struct {
+	TicketID int    `json:"ticket_id"`
+	Station  string `json:"station"`
+	Order    Order  `json:"order"` // only the items of this station
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrTableNotOpen          = errors.New("table has no open session")
+	ErrTableOrdersInProgress = errors.New("table has orders that are not ready yet")
+)
+
+// OpenTable is a table with an open session and what it has ordered so far
+type OpenTable struct {
+	SessionID   int       `json:"session_id"`
+	LocationID  string    `json:"location_id"`
+	TableNumber int       `json:"table_number"`
+	Seats       int       `json:"seats"`
+	OpenedAt    time.Time `json:"opened_at"`
+	Orders      int       `json:"orders"`
+	TotalAmount Money     `json:"total_amount"` // cancelled and already paid orders are not counted
+}
+
+// TableBill is the combined bill of a closed table session
+type TableBill struct {
+	SessionID   int         `json:"session_id"`
+	LocationID  string      `json:"location_id"`
+	TableNumber int         `json:"table_number"`
+	Seats       int         `json:"seats"`
+	OpenedAt    time.Time   `json:"opened_at"`
+	ClosedAt    time.Time   `json:"closed_at"`
+	Orders      []BillOrder `json:"orders"`
+	TotalAmount Money       `json:"total_amount"` // cancelled and already paid orders are not counted
+}
+
+type BillOrder struct {
+	OrderNumber    string      `json:"order_number"`
+	Status         string      `json:"status"`
+	Paid           bool        `json:"paid"` // paid online, not part of the bill total
+	Items          []OrderItem `json:"items"`
+	DiscountAmount Money       `json:"discount_amount"`
+	TotalAmount    Money       `json:"total_amount"`
+}
@@ -0,0 +1,7 @@
+package ports
+
+import "context"
+
+type DeliveryServiceInterface interface {
+	Start(ctx context.Context) error
+}
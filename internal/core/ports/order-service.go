@@ -8,4 +8,13 @@ import (
 type OrderServiceInterface interface {
 	Stop(ctx context.Context, server *http.Server)
 	PostOrder(w http.ResponseWriter, r *http.Request)
+	PostOrderBatch(w http.ResponseWriter, r *http.Request)
+	ModifyOrder(w http.ResponseWriter, r *http.Request)
+	GetMenu(w http.ResponseWriter, r *http.Request)
+	GetMenuModifiers(w http.ResponseWriter, r *http.Request)
+	CancelOrder(w http.ResponseWriter, r *http.Request)
+	PostPayment(w http.ResponseWriter, r *http.Request)
+	CloseTable(w http.ResponseWriter, r *http.Request)
+	PostCustomer(w http.ResponseWriter, r *http.Request)
+	GetCustomer(w http.ResponseWriter, r *http.Request)
 }
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// PaymentProvider charges and refunds orders. Declined charges return domain.ErrPaymentDeclined.
+type PaymentProvider interface {
+	Name() string
+	Charge(ctx context.Context, orderNumber string, amount domain.Money, token string) (ref string, err error)
+	Refund(ctx context.Context, chargeRef string, amount domain.Money) (ref string, err error)
+}
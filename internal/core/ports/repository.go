@@ -6,8 +6,8 @@ import (
 )
 
 type RepositoryInterface interface {
-	InsertOrder(ctx context.Context, order *domain.Order) (string, error)
-	InsertWorker(ctx context.Context, workerName string, orderTypes []string) error
+	InsertOrder(ctx context.Context, order *domain.Order, idemKey *domain.IdempotencyKey) (string, error)
+	InsertWorker(ctx context.Context, workerName string, orderTypes, stations []string, locationID string) error
 	UpdateWorkerStatus(ctx context.Context, workerName, status string) error
 	// Close(ctx context.Context, workerName string) error
 }
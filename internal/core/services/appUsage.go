@@ -8,17 +8,27 @@ var appUsage string = `Usage:
 
 Options:
   --help                  Show this screen.
-  --mode S                Required. Restaurant mode. Possible mode options (S): "order-service", "kitchen-worker", "tracking-service", "notification-subscriber".
+  --mode S                Required. Restaurant mode. Possible mode options (S): "order-service", "kitchen-worker", "delivery-courier", "tracking-service", "notification-subscriber".
 
 'Order-service' service Options:
   --port N                Default: 3000. Port number. Port number 'N' must be between 1024 and 49151 inclusively.
+  --max-concurrent N      Default: 50. Maximum number of orders processed at once, must be between 1 and 100.
+  --max-wait-ms N         Default: 500. Milliseconds an order waits for a free slot before 503 is returned, must be between 0 and 10000.
   
 'Kitchen-worker' service Options:
   --worker-name S         Required. Establishes unique name for the worker.
   --order-types S         Optional. Comma-separated list of order types the worker can handle (e.g., dine_in, takeout and delivery). If omitted, handles all.
   --heartbeat-interval N  Default: 30s. Interval (seconds) between heartbeats.
   --prefetch N            Default: 1. RabbitMQ prefetch count, limiting how many messages the worker receives at once.  
+  --location S            Default: MAIN. Restaurant location id, the worker only consumes orders of this location.
+  --stations S            Optional. Comma-separated list of kitchen stations the worker cooks at (oven, cold, fryer). If omitted, cooks at all.
   
+'Delivery-courier' service Options:
+  --courier-name S        Required. Establishes unique name for the courier.
+  --heartbeat-interval N  Default: 30s. Interval (seconds) between heartbeats.
+  --prefetch N            Default: 1. RabbitMQ prefetch count, limiting how many ready orders the courier receives at once.
+  --location S            Default: MAIN. Restaurant location id, the courier only delivers orders of this location.
+
 'Tracking-service' service Options:
   --port N                Default: 3000. Port number. Port number 'N' must be between 1024 and 49151 inclusively.
 `
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// ApplyMenuPrices takes name, price and station of new items (not stored yet) and the prices of their
+// modifiers from the catalog, the values sent by the client are ignored
+func ApplyMenuPrices(order *domain.Order, menu map[string]domain.MenuItem, modifiers map[string]domain.MenuModifier) error {
+	for i := range order.Items {
+		item := &order.Items[i]
+		if item.Stored {
+			continue
+		}
+		menuItem, ok := menu[item.SKU]
+		if !ok || !menuItem.Available {
+			return domain.ValidationErrors{{Field: fmt.Sprintf("items[%d].sku", i), Code: domain.CodeUnavailable, Message: fmt.Sprintf("%s is unknown or currently unavailable", item.SKU)}}
+		}
+		item.Name = menuItem.Name
+		item.Price = menuItem.Price
+		item.Station = menuItem.Station
+
+		for j := range item.Modifiers {
+			modifier := &item.Modifiers[j]
+			menuModifier, ok := modifiers[modifier.Code]
+			if !ok || !menuModifier.Available {
+				return domain.ValidationErrors{{Field: fmt.Sprintf("items[%d].modifiers[%d].code", i, j), Code: domain.CodeUnavailable, Message: fmt.Sprintf("%s is unknown or currently unavailable", modifier.Code)}}
+			}
+			modifier.Name = menuModifier.Name
+			modifier.Kind = menuModifier.Kind
+			modifier.PriceDelta = menuModifier.PriceDelta
+		}
+		if item.UnitPrice().Amount <= 0 {
+			return domain.ValidationErrors{{Field: fmt.Sprintf("items[%d].modifiers", i), Code: domain.CodeInvalid, Message: fmt.Sprintf("price of %s with its modifiers must be positive (got %s)", item.SKU, item.UnitPrice())}}
+		}
+	}
+	return nil
+}
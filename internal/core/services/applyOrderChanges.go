@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// ApplyOrderChanges edits the order items in place and returns a human readable diff
+func ApplyOrderChanges(order *domain.Order, req domain.ModifyOrderRequest) (string, error) {
+	if len(req.Add) == 0 && len(req.Update) == 0 && len(req.Remove) == 0 {
+		return "", domain.ValidationErrors{{Field: "", Code: domain.CodeRequired, Message: "nothing to modify: add, update and remove are empty"}}
+	}
+
+	var diff []string
+	for j, ref := range req.Remove {
+		i, err := findItem(order.Items, ref, fmt.Sprintf("remove[%d]", j))
+		if err != nil {
+			return "", err
+		}
+		diff = append(diff, fmt.Sprintf("removed %s", itemLabel(order.Items[i])))
+		order.Items = append(order.Items[:i], order.Items[i+1:]...)
+	}
+
+	for j, change := range req.Update {
+		field := fmt.Sprintf("update[%d]", j)
+		i, err := findItem(order.Items, change.Ref(), field)
+		if err != nil {
+			return "", err
+		}
+		if change.Quantity == nil && change.NewModifiers == nil {
+			return "", domain.ValidationErrors{{Field: field, Code: domain.CodeRequired, Message: "quantity or new_modifiers is required"}}
+		}
+		item := &order.Items[i]
+		if change.Quantity != nil {
+			diff = append(diff, fmt.Sprintf("%s quantity %d -> %d", itemLabel(*item), item.Quantity, *change.Quantity))
+			item.Quantity = *change.Quantity
+		}
+		if change.NewModifiers != nil {
+			for k, other := range order.Items {
+				if k != i && other.SKU == item.SKU && sameCodes(modifierCodes(other), modifierCodes(domain.OrderItem{Modifiers: *change.NewModifiers})) {
+					return "", domain.ValidationErrors{{Field: field + ".new_modifiers", Code: domain.CodeDuplicate, Message: fmt.Sprintf("%s is already in the order with these modifiers, update its quantity instead", itemLabel(other))}}
+				}
+			}
+			old := itemLabel(*item)
+			item.Modifiers = *change.NewModifiers
+			// The item is priced again with its new modifiers
+			item.Stored = false
+			diff = append(diff, fmt.Sprintf("%s -> %s", old, itemLabel(*item)))
+		}
+	}
+
+	for j, item := range req.Add {
+		codes := modifierCodes(item)
+		for _, existing := range order.Items {
+			if existing.SKU == item.SKU && sameCodes(modifierCodes(existing), codes) {
+				return "", domain.ValidationErrors{{Field: fmt.Sprintf("add[%d].sku", j), Code: domain.CodeDuplicate, Message: fmt.Sprintf("cannot add item %s: already in the order with the same modifiers, update its quantity instead", itemLabel(item))}}
+			}
+		}
+		// New items are resolved against the menu
+		item.Stored = false
+		order.Items = append(order.Items, item)
+		diff = append(diff, fmt.Sprintf("added %s x%d", itemLabel(item), item.Quantity))
+	}
+
+	return strings.Join(diff, "; "), nil
+}
+
+// findItem returns the index of the one order item ref points to
+func findItem(items []domain.OrderItem, ref domain.OrderItemRef, field string) (int, error) {
+	if ref.ItemID != 0 {
+		for i, item := range items {
+			if item.ID == ref.ItemID {
+				return i, nil
+			}
+		}
+		return -1, domain.ValidationErrors{{Field: field + ".item_id", Code: domain.CodeUnknown, Message: fmt.Sprintf("item %d is not in the order", ref.ItemID)}}
+	}
+	if ref.Name == "" {
+		return -1, domain.ValidationErrors{{Field: field, Code: domain.CodeRequired, Message: "item_id or name is required"}}
+	}
+
+	found := -1
+	for i, item := range items {
+		if item.SKU != ref.Name && item.Name != ref.Name {
+			continue
+		}
+		if ref.Modifiers != nil && !sameCodes(modifierCodes(item), ref.Modifiers) {
+			continue
+		}
+		if found != -1 {
+			return -1, domain.ValidationErrors{{Field: field + ".name", Code: domain.CodeInvalid, Message: fmt.Sprintf("%s is in the order more than once, give its item_id or modifiers", ref.Name)}}
+		}
+		found = i
+	}
+	if found == -1 {
+		return -1, domain.ValidationErrors{{Field: field + ".name", Code: domain.CodeUnknown, Message: fmt.Sprintf("%s is not in the order", ref.Name)}}
+	}
+	return found, nil
+}
+
+func modifierCodes(item domain.OrderItem) []string {
+	codes := make([]string, 0, len(item.Modifiers))
+	for _, modifier := range item.Modifiers {
+		codes = append(codes, modifier.Code)
+	}
+	return codes
+}
+
+// sameCodes compares two modifier code lists ignoring their order
+func sameCodes(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// itemLabel names an item in the diff, e.g. PIZZA-MARG (SIZE-L, NO-OLIVE)
+func itemLabel(item domain.OrderItem) string {
+	label := item.SKU
+	if label == "" {
+		label = item.Name
+	}
+	if codes := modifierCodes(item); len(codes) > 0 {
+		label += " (" + strings.Join(codes, ", ") + ")"
+	}
+	return label
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+func testOrder() domain.Order {
+	return domain.Order{Items: []domain.OrderItem{
+		{ID: 1, SKU: "PIZZA-MARG", Name: "Margherita", Quantity: 1, Stored: true},
+		{ID: 2, SKU: "PIZZA-MARG", Name: "Margherita", Quantity: 1, Stored: true, Modifiers: []domain.OrderItemModifier{{Code: "SIZE-L"}, {Code: "NO-OLIVE"}}},
+		{ID: 3, SKU: "SALAD-CAES", Name: "Caesar Salad", Quantity: 1, Stored: true},
+	}}
+}
+
+func TestApplyOrderChanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       string
+		wantErr   string      // validation error code
+		wantItems map[int]int // item id -> quantity, 0 for new items
+	}{
+		{
+			name:      "remove by sku",
+			req:       `{"remove": ["SALAD-CAES"]}`,
+			wantItems: map[int]int{1: 1, 2: 1},
+		},
+		{
+			name:    "ambiguous sku",
+			req:     `{"remove": ["PIZZA-MARG"]}`,
+			wantErr: domain.CodeInvalid,
+		},
+		{
+			name:      "remove by item id",
+			req:       `{"remove": [2]}`,
+			wantItems: map[int]int{1: 1, 3: 1},
+		},
+		{
+			name:      "update by sku and modifiers",
+			req:       `{"update": [{"name": "PIZZA-MARG", "modifiers": ["NO-OLIVE", "SIZE-L"], "quantity": 3}]}`,
+			wantItems: map[int]int{1: 1, 2: 3, 3: 1},
+		},
+		{
+			name:      "plain item by empty modifiers",
+			req:       `{"update": [{"name": "PIZZA-MARG", "modifiers": [], "quantity": 2}]}`,
+			wantItems: map[int]int{1: 2, 2: 1, 3: 1},
+		},
+		{
+			name:    "unknown item id",
+			req:     `{"update": [{"item_id": 9, "quantity": 2}]}`,
+			wantErr: domain.CodeUnknown,
+		},
+		{
+			name:    "update without a change",
+			req:     `{"update": [{"item_id": 1}]}`,
+			wantErr: domain.CodeRequired,
+		},
+		{
+			name:      "add an sku with other modifiers",
+			req:       `{"add": [{"sku": "PIZZA-MARG", "quantity": 1, "modifiers": [{"code": "SIZE-XL"}]}]}`,
+			wantItems: map[int]int{1: 1, 2: 1, 3: 1, 0: 1},
+		},
+		{
+			name:    "add an sku with the same modifiers",
+			req:     `{"add": [{"sku": "PIZZA-MARG", "quantity": 1, "modifiers": [{"code": "NO-OLIVE"}, {"code": "SIZE-L"}]}]}`,
+			wantErr: domain.CodeDuplicate,
+		},
+		{
+			name:    "modifiers made equal to another item",
+			req:     `{"update": [{"item_id": 1, "new_modifiers": [{"code": "SIZE-L"}, {"code": "NO-OLIVE"}]}]}`,
+			wantErr: domain.CodeDuplicate,
+		},
+		{
+			name:    "nothing to modify",
+			req:     `{}`,
+			wantErr: domain.CodeRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req domain.ModifyOrderRequest
+			if err := json.Unmarshal([]byte(tt.req), &req); err != nil {
+				t.Fatalf("cannot decode %s: %v", tt.req, err)
+			}
+			order := testOrder()
+			_, err := ApplyOrderChanges(&order, req)
+			if tt.wantErr != "" {
+				var errs domain.ValidationErrors
+				if !errors.As(err, &errs) || errs[0].Code != tt.wantErr {
+					t.Fatalf("ApplyOrderChanges() error = %v, want code %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyOrderChanges() error = %v", err)
+			}
+			got := make(map[int]int)
+			for _, item := range order.Items {
+				got[item.ID] = item.Quantity
+			}
+			if len(got) != len(tt.wantItems) {
+				t.Fatalf("ApplyOrderChanges() items = %v, want %v", got, tt.wantItems)
+			}
+			for id, quantity := range tt.wantItems {
+				if got[id] != quantity {
+					t.Errorf("ApplyOrderChanges() items = %v, want %v", got, tt.wantItems)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOrderChangesNewModifiers(t *testing.T) {
+	order := testOrder()
+	req := domain.ModifyOrderRequest{Update: []domain.OrderItemChange{{ItemID: 3, NewModifiers: &[]domain.OrderItemModifier{{Code: "EXTRA-DIP"}}}}}
+	diff, err := ApplyOrderChanges(&order, req)
+	if err != nil {
+		t.Fatalf("ApplyOrderChanges() error = %v", err)
+	}
+	item := order.Items[2]
+	if item.Stored || len(item.Modifiers) != 1 || item.Modifiers[0].Code != "EXTRA-DIP" {
+		t.Errorf("ApplyOrderChanges() item = %+v, want it repriced with EXTRA-DIP", item)
+	}
+	if want := "SALAD-CAES -> SALAD-CAES (EXTRA-DIP)"; diff != want {
+		t.Errorf("ApplyOrderChanges() diff = %q, want %q", diff, want)
+	}
+}
@@ -1,19 +1,36 @@
 package services
 
-import "wheres-my-pizza/internal/core/domain"
+import (
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+)
 
-func AssignPriority(order domain.Order) int {
-	var totalAmount float64
-	for _, item := range order.Items {
-		totalAmount += float64(item.Quantity) * item.Price
-	}
+var (
+	defaultHighThreshold   = domain.NewMoney(100_00)
+	defaultMediumThreshold = domain.NewMoney(50_00)
+)
 
+// assignDefaultPriority applies the thresholds: 10 above high, 5 from medium, 1 otherwise
+func assignDefaultPriority(totalAmount, highThreshold, mediumThreshold domain.Money) (int, string) {
 	switch {
-	case totalAmount > 100:
-		return 10
-	case totalAmount >= 50:
-		return 5
+	case highThreshold.LessThan(totalAmount):
+		return 10, fmt.Sprintf("total %s > %s: base priority 10", totalAmount, highThreshold)
+	case !totalAmount.LessThan(mediumThreshold):
+		return 5, fmt.Sprintf("total %s >= %s: base priority 5", totalAmount, mediumThreshold)
 	default:
-		return 1
+		return 1, fmt.Sprintf("total %s < %s: base priority 1", totalAmount, mediumThreshold)
+	}
+}
+
+// OrderTotal is what the customer pays: the items minus the discount
+func OrderTotal(order domain.Order) domain.Money {
+	return orderSubtotal(order).Sub(order.DiscountAmount)
+}
+
+func orderSubtotal(order domain.Order) domain.Money {
+	totalAmount := domain.NewMoney(0)
+	for _, item := range order.Items {
+		totalAmount = totalAmount.Add(item.UnitPrice().Mul(item.Quantity))
 	}
+	return totalAmount
 }
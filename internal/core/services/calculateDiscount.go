@@ -0,0 +1,51 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// CalculateDiscount returns the discount of the promotion for the order items,
+// never more than the order subtotal
+func CalculateDiscount(order domain.Order, promo domain.Promotion) domain.Money {
+	subtotal := orderSubtotal(order)
+
+	discount := domain.NewMoney(0)
+	switch promo.RuleType {
+	case "percent_off":
+		if promo.Percent != nil {
+			discount = subtotal.Percent(*promo.Percent)
+		}
+	case "amount_off":
+		if promo.Amount != nil {
+			discount = *promo.Amount
+		}
+	case "bundle_price":
+		if promo.Amount == nil || promo.SKUPrefix == nil || promo.BundleQuantity == nil || *promo.BundleQuantity < 1 {
+			break
+		}
+		// The most expensive matching units are bundled first
+		var unitPrices []domain.Money
+		for _, item := range order.Items {
+			if strings.HasPrefix(item.SKU, *promo.SKUPrefix) {
+				for range item.Quantity {
+					unitPrices = append(unitPrices, item.UnitPrice())
+				}
+			}
+		}
+		sort.Slice(unitPrices, func(i, j int) bool { return unitPrices[j].LessThan(unitPrices[i]) })
+		size := *promo.BundleQuantity
+		for i := 0; i+size <= len(unitPrices); i += size {
+			bundleTotal := domain.NewMoney(0)
+			for _, price := range unitPrices[i : i+size] {
+				bundleTotal = bundleTotal.Add(price)
+			}
+			if promo.Amount.LessThan(bundleTotal) {
+				discount = discount.Add(bundleTotal.Sub(*promo.Amount))
+			}
+		}
+	}
+
+	return domain.MinMoney(discount, subtotal)
+}
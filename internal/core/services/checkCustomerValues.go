@@ -0,0 +1,37 @@
+package services
+
+import (
+	"regexp"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+var (
+	validPhoneRegex = regexp.MustCompile(`^\+?[0-9][0-9 \-]{5,18}[0-9]$`)
+	validEmailRegex = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// CheckCustomerValues validates a customer, prefix is the JSON path of the customer object
+// (empty for the top level). At least a phone or an email is required to recognize the customer.
+func CheckCustomerValues(customer domain.Customer, prefix string) domain.ValidationErrors {
+	var errs domain.ValidationErrors
+	field := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	if !validStringRegex.MatchString(customer.Name) {
+		errs = append(errs, domain.FieldError{Field: field("name"), Code: domain.CodeInvalid, Message: "must be 1–100 characters, only letters, spaces, hyphens, and apostrophes (got " + customer.Name + ")"})
+	}
+	if customer.Phone == nil && customer.Email == nil {
+		errs = append(errs, domain.FieldError{Field: field("phone"), Code: domain.CodeRequired, Message: "phone or email is required"})
+	}
+	if customer.Phone != nil && !validPhoneRegex.MatchString(*customer.Phone) {
+		errs = append(errs, domain.FieldError{Field: field("phone"), Code: domain.CodeInvalid, Message: "must be 7–20 digits, spaces or hyphens, optionally starting with + (got " + *customer.Phone + ")"})
+	}
+	if customer.Email != nil && (len(*customer.Email) > 254 || !validEmailRegex.MatchString(*customer.Email)) {
+		errs = append(errs, domain.FieldError{Field: field("email"), Code: domain.CodeInvalid, Message: "must be a valid email address (got " + *customer.Email + ")"})
+	}
+	return errs
+}
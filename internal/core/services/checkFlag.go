@@ -3,11 +3,13 @@ package services
 import (
 	"errors"
 	"fmt"
+	"slices"
 
+	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/internal/core/utils.go"
 )
 
-func CheckFlags(mode, workerName, orderTypes string, port, maxConcurrent, heartbeatInterval, prefetch int, isSetByUser bool) error {
+func CheckFlags(mode, workerName, courierName, orderTypes, stations, location string, port, maxConcurrent, maxWaitMs, heartbeatInterval, prefetch int, isSetByUser bool) error {
 	switch mode {
 	case "order-service":
 		if err := utils.CheckPort(port, isSetByUser); err != nil {
@@ -17,6 +19,10 @@ func CheckFlags(mode, workerName, orderTypes string, port, maxConcurrent, heartb
 			errMessage := fmt.Sprintf("invalid 'max-concurrent' value: %d", maxConcurrent)
 			return errors.New(errMessage)
 		}
+		if maxWaitMs < 0 || maxWaitMs > 10000 {
+			errMessage := fmt.Sprintf("invalid 'max-wait-ms' value: %d", maxWaitMs)
+			return errors.New(errMessage)
+		}
 	case "kitchen-worker":
 		if workerName == "" {
 			errMessage := "'worker-name' value cannot be empty"
@@ -34,6 +40,12 @@ func CheckFlags(mode, workerName, orderTypes string, port, maxConcurrent, heartb
 				return errors.New(errMessage)
 			}
 		}
+		for _, station := range utils.GetStringArray(stations) {
+			if !slices.Contains(domain.Stations, station) {
+				errMessage := fmt.Sprintf("invalid 'stations' value: %s", station)
+				return errors.New(errMessage)
+			}
+		}
 		if heartbeatInterval <= 0 || heartbeatInterval > 50 {
 			errMessage := fmt.Sprintf("invalid 'heartbeat-interval' value: %d", heartbeatInterval)
 			return errors.New(errMessage)
@@ -42,6 +54,27 @@ func CheckFlags(mode, workerName, orderTypes string, port, maxConcurrent, heartb
 			errMessage := fmt.Sprintf("invalid 'prefetch' value: %d", prefetch)
 			return errors.New(errMessage)
 		}
+		if !validLocationRegex.MatchString(location) {
+			errMessage := fmt.Sprintf("invalid 'location' value: %s", location)
+			return errors.New(errMessage)
+		}
+	case "delivery-courier":
+		if courierName == "" {
+			errMessage := "'courier-name' value cannot be empty"
+			return errors.New(errMessage)
+		}
+		if heartbeatInterval <= 0 || heartbeatInterval > 50 {
+			errMessage := fmt.Sprintf("invalid 'heartbeat-interval' value: %d", heartbeatInterval)
+			return errors.New(errMessage)
+		}
+		if prefetch <= 0 || prefetch > 10 {
+			errMessage := fmt.Sprintf("invalid 'prefetch' value: %d", prefetch)
+			return errors.New(errMessage)
+		}
+		if !validLocationRegex.MatchString(location) {
+			errMessage := fmt.Sprintf("invalid 'location' value: %s", location)
+			return errors.New(errMessage)
+		}
 	case "tracking-service":
 		if err := utils.CheckPort(port, isSetByUser); err != nil {
 			return err
@@ -6,66 +6,158 @@ import (
 	"wheres-my-pizza/internal/core/domain"
 )
 
-var validStringRegex = regexp.MustCompile(`^[a-zA-Z\s\-']{1,100}$`)
+var (
+	validStringRegex   = regexp.MustCompile(`^[a-zA-Z\s\-']{1,100}$`)
+	validLocationRegex = regexp.MustCompile(`^[A-Z0-9]{2,8}$`)
+)
+
+const maxItemModifiers = 10
+
+// CheckOrderValues validates the order, new items (not stored yet) and their modifiers must be
+// available in menu and modifiers. Every violation is collected, the returned error is domain.ValidationErrors.
+func CheckOrderValues(order domain.Order, menu map[string]domain.MenuItem, modifiers map[string]domain.MenuModifier) error {
+	var errs domain.ValidationErrors
+	add := func(field, code, format string, args ...any) {
+		errs = append(errs, domain.FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
 
-func CheckOrderValues(order domain.Order) error {
 	// Customer name
 	if !validStringRegex.MatchString(order.CustomerName) {
-		return fmt.Errorf("invalid customer_name: must be 1–100 characters, only letters, spaces, hyphens, and apostrophes (got %s)", order.CustomerName)
+		add("customer_name", domain.CodeInvalid, "must be 1–100 characters, only letters, spaces, hyphens, and apostrophes (got %s)", order.CustomerName)
+	}
+
+	// Location, the default one is used when empty
+	if order.LocationID != "" && !validLocationRegex.MatchString(order.LocationID) {
+		add("location_id", domain.CodeInvalid, "must be 2–8 uppercase letters or digits (got %s)", order.LocationID)
+	}
+
+	// Customer, either an existing id or the details to find or create one
+	if order.CustomerID != nil && *order.CustomerID < 1 {
+		add("customer_id", domain.CodeInvalid, "must be positive (got %d)", *order.CustomerID)
+	}
+	if order.CustomerID != nil && order.Customer != nil {
+		add("customer", domain.CodeNotAllowed, "must be empty when customer_id is set")
+	} else if order.Customer != nil {
+		customer := *order.Customer
+		if customer.Name == "" {
+			customer.Name = order.CustomerName
+		}
+		errs = append(errs, CheckCustomerValues(customer, "customer")...)
+	}
+
+	// Payment method, counter when empty
+	if order.PaymentMethod != "" && order.PaymentMethod != domain.PaymentMethodCounter && order.PaymentMethod != domain.PaymentMethodOnline {
+		add("payment_method", domain.CodeInvalid, "must be one of [counter, online] (got %s)", order.PaymentMethod)
 	}
 
 	// Order type
 	if !(order.Type == "dine_in" || order.Type == "takeout" || order.Type == "delivery") {
-		return fmt.Errorf("invalid order_type: must be one of [dine_in, takeout, delivery] (got %s)", order.Type)
+		add("order_type", domain.CodeInvalid, "must be one of [dine_in, takeout, delivery] (got %s)", order.Type)
 	}
 
 	// Dine-in
-	if order.Type == "dine_in" && (order.TableNumber == nil || *order.TableNumber < 1 || *order.TableNumber > 100) {
+	if order.Type == "dine_in" {
 		if order.TableNumber == nil {
-			return fmt.Errorf("invalid table_number: number must be 1 - 100 (got nil)")
+			add("table_number", domain.CodeRequired, "number must be 1 - 100 (got nil)")
+		} else if *order.TableNumber < 1 || *order.TableNumber > 100 {
+			add("table_number", domain.CodeOutOfRange, "number must be 1 - 100 (got %d)", *order.TableNumber)
+		}
+		if order.DeliveryAddress != nil {
+			add("delivery_address", domain.CodeNotAllowed, "must be empty when type='dine_in'")
 		}
-		return fmt.Errorf("invalid table_number: number must be 1 - 100 (got %d)", *order.TableNumber)
-	}
-	if order.Type == "dine_in" && order.DeliveryAddress != nil {
-		return fmt.Errorf("invalid delivery_address: must be empty when type='dine_in'")
 	}
 
 	// Delivery
-	if order.Type == "delivery" && (order.DeliveryAddress == nil || len(*order.DeliveryAddress) < 10) {
+	if order.Type == "delivery" {
 		if order.DeliveryAddress == nil {
-			return fmt.Errorf("invalid delivery_address: must be more than 10 characters (got nil)")
+			add("delivery_address", domain.CodeRequired, "must be more than 10 characters (got nil)")
+		} else if len(*order.DeliveryAddress) < 10 {
+			add("delivery_address", domain.CodeInvalid, "must be more than 10 characters (got %d)", len(*order.DeliveryAddress))
+		}
+		if order.TableNumber != nil {
+			add("table_number", domain.CodeNotAllowed, "must be empty when type='delivery'")
 		}
-		return fmt.Errorf("invalid delivery_address: must be more than 10 characters (got %d)", len(*order.DeliveryAddress))
-	}
-	if order.Type == "delivery" && order.TableNumber != nil {
-		return fmt.Errorf("invalid table_number: must be empty when type='delivery'")
 	}
 
 	// Takeout
-	if order.Type == "takeout" && order.DeliveryAddress != nil {
-		return fmt.Errorf("invalid delivery_address: must be empty when type='takeout'")
-	}
-	if order.Type == "takeout" && order.TableNumber != nil {
-		return fmt.Errorf("invalid table_number: must be empty when type='takeout'")
+	if order.Type == "takeout" {
+		if order.DeliveryAddress != nil {
+			add("delivery_address", domain.CodeNotAllowed, "must be empty when type='takeout'")
+		}
+		if order.TableNumber != nil {
+			add("table_number", domain.CodeNotAllowed, "must be empty when type='takeout'")
+		}
 	}
 
 	// Items
 	if len(order.Items) < 1 || len(order.Items) > 20 {
-		return fmt.Errorf("items count is invalid: got %d, allowed 1 - 20", len(order.Items))
+		add("items", domain.CodeOutOfRange, "items count is invalid: got %d, allowed 1 - 20", len(order.Items))
 	}
 
 	// Validate each item
 	for i, item := range order.Items {
-		if len(item.Name) < 1 || len(item.Name) > 50 {
-			return fmt.Errorf("item[%d].name is invalid: length must be 1 - 50", i)
+		if !item.Stored {
+			field := fmt.Sprintf("items[%d].sku", i)
+			menuItem, ok := menu[item.SKU]
+			switch {
+			case item.SKU == "":
+				add(field, domain.CodeRequired, "value is empty")
+			case !ok:
+				add(field, domain.CodeUnknown, "unknown menu item %s", item.SKU)
+			case !menuItem.Available:
+				add(field, domain.CodeUnavailable, "%s is currently unavailable", item.SKU)
+			}
+			checkItemModifiers(i, item, menuItem, modifiers, add)
 		}
 		if item.Quantity < 1 || item.Quantity > 10 {
-			return fmt.Errorf("item[%d].quantity is invalid: got %d, allowed 1 - 10", i, item.Quantity)
-		}
-		if item.Price < 0.01 || item.Price > 999.99 {
-			return fmt.Errorf("item[%d].price is invalid: got %.2f, allowed 0.01 - 999.99", i, item.Price)
+			add(fmt.Sprintf("items[%d].quantity", i), domain.CodeOutOfRange, "got %d, allowed 1 - 10", item.Quantity)
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
+
+// checkItemModifiers validates the modifiers of a new item: known, available, not repeated,
+// at most one size, and the item price with the modifiers stays positive
+func checkItemModifiers(i int, item domain.OrderItem, menuItem domain.MenuItem, modifiers map[string]domain.MenuModifier, add func(field, code, format string, args ...any)) {
+	if len(item.Modifiers) > maxItemModifiers {
+		add(fmt.Sprintf("items[%d].modifiers", i), domain.CodeOutOfRange, "got %d modifiers, allowed 0 - %d", len(item.Modifiers), maxItemModifiers)
+	}
+
+	seen := make(map[string]bool)
+	var sizes int
+	unitPrice := menuItem.Price
+	for j, modifier := range item.Modifiers {
+		field := fmt.Sprintf("items[%d].modifiers[%d].code", i, j)
+		menuModifier, ok := modifiers[modifier.Code]
+		switch {
+		case modifier.Code == "":
+			add(field, domain.CodeRequired, "value is empty")
+			continue
+		case seen[modifier.Code]:
+			add(field, domain.CodeDuplicate, "%s is already applied to this item", modifier.Code)
+			continue
+		case !ok:
+			add(field, domain.CodeUnknown, "unknown modifier %s", modifier.Code)
+			continue
+		case !menuModifier.Available:
+			add(field, domain.CodeUnavailable, "%s is currently unavailable", modifier.Code)
+		}
+		seen[modifier.Code] = true
+		unitPrice = unitPrice.Add(menuModifier.PriceDelta)
+
+		if menuModifier.Kind == "size" {
+			if sizes++; sizes > 1 {
+				add(field, domain.CodeNotAllowed, "only one size can be chosen (got %s as well)", modifier.Code)
+			}
+		}
+	}
+
+	// The price is only known when the item itself is in the menu
+	if menuItem.SKU != "" && unitPrice.Amount <= 0 {
+		add(fmt.Sprintf("items[%d].modifiers", i), domain.CodeInvalid, "price of %s with its modifiers must be positive (got %s)", item.SKU, unitPrice)
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// CheckPromotion checks that the promotion can be redeemed by the order at the given time
+func CheckPromotion(promo domain.Promotion, order domain.Order, at time.Time) error {
+	invalid := func(format string, args ...any) error {
+		return domain.ValidationErrors{{Field: "promo_code", Code: domain.CodeNotAllowed, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if !promo.Active {
+		return invalid("promotion %s is not active", promo.Code)
+	}
+	if at.Before(promo.ValidFrom) || (promo.ValidUntil != nil && !at.Before(*promo.ValidUntil)) {
+		return invalid("promotion %s is not valid at this time", promo.Code)
+	}
+	if promo.MaxUses != nil && promo.Uses >= *promo.MaxUses {
+		return invalid("promotion %s has reached its usage limit", promo.Code)
+	}
+	if promo.OrderType != nil && *promo.OrderType != order.Type {
+		return invalid("promotion %s is only valid for %s orders", promo.Code, *promo.OrderType)
+	}
+	if promo.Weekday != nil && int(at.Weekday()) != *promo.Weekday {
+		return invalid("promotion %s is only valid on %s", promo.Code, time.Weekday(*promo.Weekday))
+	}
+	if subtotal := orderSubtotal(order); subtotal.LessThan(promo.MinTotal) {
+		return invalid("promotion %s requires a total of at least %s (got %s)", promo.Code, promo.MinTotal, subtotal)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package services
+
+// CookingTimeSeconds is the expected cooking time of an order type
+func CookingTimeSeconds(orderType string) int {
+	switch orderType {
+	case "dine_in":
+		return 8
+	case "takeout":
+		return 10
+	case "delivery":
+		return 12
+	}
+	return 0
+}
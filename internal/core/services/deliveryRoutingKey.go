@@ -0,0 +1,11 @@
+package services
+
+import (
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// DeliveryRoutingKey returns the orders_topic routing key of a ready delivery order: delivery.{location}.ready
+func DeliveryRoutingKey(order domain.Order) string {
+	return fmt.Sprintf("delivery.%s.ready", order.LocationID)
+}
@@ -0,0 +1,4 @@
+package services
+
+// DeliveryTimeSeconds is the expected time from leaving the restaurant to the customer's door
+const DeliveryTimeSeconds = 15
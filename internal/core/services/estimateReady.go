@@ -0,0 +1,16 @@
+package services
+
+import (
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// EstimateReady estimates when an order waiting in the kitchen queue is ready. Every online worker cooks
+// one order at a time and cooking orders are assumed half done. Without an online worker the order is
+// estimated as if one started now.
+func EstimateReady(load domain.KitchenLoad, now time.Time) time.Time {
+	workers := max(load.Workers, 1)
+	wait := (float64(load.Ahead) + float64(load.Cooking)/2) / float64(workers)
+	seconds := (wait + 1) * load.CookSeconds
+	return now.Add(time.Duration(seconds * float64(time.Second))).Truncate(time.Second)
+}
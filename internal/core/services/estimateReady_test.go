@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+func TestEstimateReady(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		load domain.KitchenLoad
+		want time.Duration
+	}{
+		{name: "empty kitchen", load: domain.KitchenLoad{Workers: 2, CookSeconds: 600}, want: 10 * time.Minute},
+		{name: "no worker online", load: domain.KitchenLoad{CookSeconds: 600}, want: 10 * time.Minute},
+		{name: "queue shared by workers", load: domain.KitchenLoad{Ahead: 4, Workers: 2, CookSeconds: 600}, want: 30 * time.Minute},
+		{name: "cooking orders are half done", load: domain.KitchenLoad{Cooking: 2, Workers: 1, CookSeconds: 600}, want: 20 * time.Minute},
+		{name: "truncated to seconds", load: domain.KitchenLoad{Ahead: 1, Workers: 3, CookSeconds: 100}, want: 133 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateReady(tt.load, now); !got.Equal(now.Add(tt.want)) {
+				t.Errorf("EstimateReady() = %s, want %s", got.Sub(now), tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"wheres-my-pizza/internal/core/domain"
 	"wheres-my-pizza/internal/core/utils.go"
 )
 
@@ -13,17 +14,28 @@ type KitchenFlags struct {
 	OrderTypes        []string
 	HeartbeatInterval int
 	Prefetch          int
+	LocationID        string
+	Stations          []string
+}
+
+type CourierFlags struct {
+	CourierName       string
+	HeartbeatInterval int
+	Prefetch          int
+	LocationID        string
 }
 
 type OrderFlags struct {
 	Port          int
 	MaxConcurrent int
+	MaxWaitMs     int
 }
 
 type Flags struct {
 	Mode    string
 	Order   OrderFlags
 	Kitchen KitchenFlags
+	Courier CourierFlags
 }
 
 func FlagParse() (Flags, error) {
@@ -33,12 +45,19 @@ func FlagParse() (Flags, error) {
 	mode := flag.String("mode", "", "Establishing the working mode for the app.")
 	port := flag.Int("port", 0, "The HTTP port for the API.")
 	maxConcurrent := flag.Int("max-concurrent", 50, "Maximum number of concurrent orders to process.")
+	maxWaitMs := flag.Int("max-wait-ms", 500, "Maximum time (milliseconds) an order waits for a free slot before it is rejected.")
 
 	// Kitchen-service
 	workerName := flag.String("worker-name", "", "Unique name for worker")
 	orderTypes := flag.String("order-types", "takeout, dine_in, delivery", "Optional. Comma-separated list of order types the worker can handle (e.g., dine_in,takeout). If omitted, handles all.")
 	heartbeatInterval := flag.Int("heartbeat-interval", 30, "Maximum number of concurrent orders to process.")
 	prefetch := flag.Int("prefetch", 1, "RabbitMQ prefetch count, limiting how many messages the worker receives at once.")
+	location := flag.String("location", domain.DefaultLocation, "Restaurant location id, the worker only consumes orders of this location.")
+
+	stations := flag.String("stations", "oven, cold, fryer", "Optional. Comma-separated list of kitchen stations the worker cooks at (oven, cold, fryer). If omitted, cooks at all.")
+
+	// Delivery-courier, also uses heartbeat-interval, prefetch and location
+	courierName := flag.String("courier-name", "", "Unique name for courier")
 
 	flag.Parse()
 
@@ -55,7 +74,7 @@ func FlagParse() (Flags, error) {
 	}
 
 	// Checking for flag values
-	err := CheckFlags(*mode, *workerName, *orderTypes, *port, *maxConcurrent, *heartbeatInterval, *prefetch, isSetByUser)
+	err := CheckFlags(*mode, *workerName, *courierName, *orderTypes, *stations, *location, *port, *maxConcurrent, *maxWaitMs, *heartbeatInterval, *prefetch, isSetByUser)
 	if err != nil {
 		return Flags{}, err
 	}
@@ -66,12 +85,15 @@ func FlagParse() (Flags, error) {
 		if !isSetByUser {
 			*port = 3000
 		}
-		orderFlags := OrderFlags{Port: *port, MaxConcurrent: *maxConcurrent}
+		orderFlags := OrderFlags{Port: *port, MaxConcurrent: *maxConcurrent, MaxWaitMs: *maxWaitMs}
 		return Flags{Mode: *mode, Order: orderFlags}, nil
 	case "kitchen-worker":
 		orderTypesArr := utils.GetStringArray(*orderTypes)
-		kitchenFlags := KitchenFlags{WorkerName: *workerName, OrderTypes: orderTypesArr, HeartbeatInterval: *heartbeatInterval, Prefetch: *prefetch}
+		kitchenFlags := KitchenFlags{WorkerName: *workerName, OrderTypes: orderTypesArr, HeartbeatInterval: *heartbeatInterval, Prefetch: *prefetch, LocationID: *location, Stations: utils.GetStringArray(*stations)}
 		return Flags{Mode: *mode, Kitchen: kitchenFlags}, nil
+	case "delivery-courier":
+		courierFlags := CourierFlags{CourierName: *courierName, HeartbeatInterval: *heartbeatInterval, Prefetch: *prefetch, LocationID: *location}
+		return Flags{Mode: *mode, Courier: courierFlags}, nil
 	case "tracking-service":
 		if !isSetByUser {
 			*port = 3002
@@ -0,0 +1,17 @@
+package services
+
+import "wheres-my-pizza/internal/core/domain"
+
+// GetItemSKUs returns the distinct SKUs of the order items
+func GetItemSKUs(items []domain.OrderItem) []string {
+	seen := make(map[string]bool)
+	var skus []string
+	for _, item := range items {
+		if item.SKU == "" || seen[item.SKU] {
+			continue
+		}
+		seen[item.SKU] = true
+		skus = append(skus, item.SKU)
+	}
+	return skus
+}
@@ -0,0 +1,19 @@
+package services
+
+import "wheres-my-pizza/internal/core/domain"
+
+// GetModifierCodes returns the distinct modifier codes of the order items
+func GetModifierCodes(items []domain.OrderItem) []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, item := range items {
+		for _, modifier := range item.Modifiers {
+			if modifier.Code == "" || seen[modifier.Code] {
+				continue
+			}
+			seen[modifier.Code] = true
+			codes = append(codes, modifier.Code)
+		}
+	}
+	return codes
+}
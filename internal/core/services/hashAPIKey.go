@@ -0,0 +1,12 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashAPIKey is the value stored in api_keys.key_hash, keys are random so a plain SHA-256 is enough
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
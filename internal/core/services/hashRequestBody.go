@@ -0,0 +1,11 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+func HashRequestBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
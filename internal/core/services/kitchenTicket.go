@@ -0,0 +1,24 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// KitchenTicket returns one line per item, e.g. "2x Margherita Pizza (Large, Extra Cheese, No Olives)"
+func KitchenTicket(order domain.Order) []string {
+	lines := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		line := fmt.Sprintf("%dx %s", item.Quantity, item.Name)
+		if len(item.Modifiers) > 0 {
+			names := make([]string, 0, len(item.Modifiers))
+			for _, modifier := range item.Modifiers {
+				names = append(names, modifier.Name)
+			}
+			line += " (" + strings.Join(names, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,20 @@
+package services
+
+import "wheres-my-pizza/internal/core/domain"
+
+// NewPutOrderResponse describes a saved or modified order to the client
+func NewPutOrderResponse(order *domain.Order) domain.PutOrderResponse {
+	return domain.PutOrderResponse{
+		OrderNumber:      order.Number,
+		Status:           order.Status,
+		TotalAmount:      order.TotalAmount,
+		ScheduledFor:     order.ScheduledFor,
+		PromoCode:        order.PromoCode,
+		DiscountAmount:   order.DiscountAmount,
+		CustomerID:       order.CustomerID,
+		LocationID:       order.LocationID,
+		PaymentMethod:    order.PaymentMethod,
+		TableSessionID:   order.TableSessionID,
+		EstimatedReadyAt: order.EstimatedReadyAt,
+	}
+}
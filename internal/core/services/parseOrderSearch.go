@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+var orderStatuses = map[string]bool{"scheduled": true, "awaiting_payment": true, "received": true, "paid": true, "cooking": true, "ready": true, "out_for_delivery": true, "delivered": true, "cancelled": true}
+
+// ParseOrderSearch reads the GET /orders query: status (comma separated), type, from, to (RFC 3339),
+// customer (id or name prefix), worker, location, sort (created_at, priority), order (asc, desc),
+// limit and cursor. Every violation is collected, the returned error is domain.ValidationErrors.
+func ParseOrderSearch(query url.Values) (domain.OrderSearchFilter, error) {
+	var errs domain.ValidationErrors
+	add := func(field, code, format string, args ...any) {
+		errs = append(errs, domain.FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	filter := domain.OrderSearchFilter{SortBy: "created_at", Desc: true, Limit: DefaultPageLimit}
+
+	if value := query.Get("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if !orderStatuses[status] {
+				add("status", domain.CodeInvalid, "must be one of [scheduled, awaiting_payment, received, paid, cooking, ready, out_for_delivery, delivered, cancelled] (got %s)", status)
+				continue
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if value := query.Get("type"); value != "" {
+		if !(value == "dine_in" || value == "takeout" || value == "delivery") {
+			add("type", domain.CodeInvalid, "must be one of [dine_in, takeout, delivery] (got %s)", value)
+		}
+		filter.Type = value
+	}
+
+	for _, field := range []string{"from", "to"} {
+		value := query.Get(field)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			add(field, domain.CodeInvalid, "must be an RFC 3339 time, e.g. 2026-10-18T12:00:00Z (got %s)", value)
+			continue
+		}
+		if field == "from" {
+			filter.From = &t
+		} else {
+			filter.To = &t
+		}
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		add("to", domain.CodeOutOfRange, "must be after from")
+	}
+
+	if value := strings.TrimSpace(query.Get("customer")); value != "" {
+		if id, err := strconv.Atoi(value); err == nil {
+			filter.CustomerID = &id
+		} else {
+			filter.CustomerName = value
+		}
+	}
+	filter.Worker = strings.TrimSpace(query.Get("worker"))
+	filter.LocationID = strings.TrimSpace(query.Get("location"))
+
+	if value := query.Get("sort"); value != "" {
+		if value != "created_at" && value != "priority" {
+			add("sort", domain.CodeInvalid, "must be one of [created_at, priority] (got %s)", value)
+		}
+		filter.SortBy = value
+	}
+	if value := query.Get("order"); value != "" {
+		if value != "asc" && value != "desc" {
+			add("order", domain.CodeInvalid, "must be one of [asc, desc] (got %s)", value)
+		}
+		filter.Desc = value != "asc"
+	}
+
+	if value := query.Get("limit"); value != "" {
+		num, err := strconv.Atoi(value)
+		if err != nil || num < 1 || num > MaxPageLimit {
+			add("limit", domain.CodeOutOfRange, "must be 1 - %d (got %s)", MaxPageLimit, value)
+		}
+		filter.Limit = num
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := DecodeOrderCursor(value)
+		if err != nil {
+			add("cursor", domain.CodeInvalid, "is not a cursor returned by this endpoint")
+		} else if cursor.SortBy != filter.SortBy || cursor.Desc != filter.Desc {
+			add("cursor", domain.CodeInvalid, "was returned for a different sort or order, start again without a cursor")
+		}
+		filter.After = &cursor
+	}
+
+	if len(errs) > 0 {
+		return filter, errs
+	}
+	return filter, nil
+}
+
+// EncodeOrderCursor returns the opaque next_cursor that continues after order in the sort of filter
+func EncodeOrderCursor(filter domain.OrderSearchFilter, order domain.OrderSummary) string {
+	data, _ := json.Marshal(domain.OrderCursor{SortBy: filter.SortBy, Desc: filter.Desc, CreatedAt: order.CreatedAt, Priority: order.Priority, ID: order.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeOrderCursor(value string) (domain.OrderCursor, error) {
+	var cursor domain.OrderCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, err
+	}
+	if cursor.ID < 1 {
+		return cursor, fmt.Errorf("invalid cursor id %d", cursor.ID)
+	}
+	return cursor, nil
+}
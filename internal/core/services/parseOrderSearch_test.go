@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+func TestOrderCursor(t *testing.T) {
+	filter, err := ParseOrderSearch(url.Values{"sort": {"priority"}, "order": {"asc"}})
+	if err != nil {
+		t.Fatalf("ParseOrderSearch() error = %v", err)
+	}
+	last := domain.OrderSummary{ID: 42, Priority: 5, CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	cursor := EncodeOrderCursor(filter, last)
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		wantErr bool
+	}{
+		{name: "same sort", query: url.Values{"sort": {"priority"}, "order": {"asc"}, "cursor": {cursor}}},
+		{name: "other sort", query: url.Values{"sort": {"created_at"}, "order": {"asc"}, "cursor": {cursor}}, wantErr: true},
+		{name: "other order", query: url.Values{"sort": {"priority"}, "cursor": {cursor}}, wantErr: true},
+		{name: "garbage", query: url.Values{"sort": {"priority"}, "order": {"asc"}, "cursor": {"not-a-cursor"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseOrderSearch(tt.query)
+			if tt.wantErr {
+				var errs domain.ValidationErrors
+				if !errors.As(err, &errs) || errs[0].Field != "cursor" {
+					t.Fatalf("ParseOrderSearch() error = %v, want a cursor validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOrderSearch() error = %v", err)
+			}
+			after := filter.After
+			if after == nil || after.ID != last.ID || after.Priority != last.Priority || !after.CreatedAt.Equal(last.CreatedAt) {
+				t.Errorf("ParseOrderSearch() cursor = %+v, want the position of %+v", after, last)
+			}
+		})
+	}
+}
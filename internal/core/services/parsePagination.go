@@ -0,0 +1,39 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// ParsePagination reads the limit and offset query parameters
+func ParsePagination(query url.Values) (int, int, error) {
+	var errs domain.ValidationErrors
+	limit, offset := DefaultPageLimit, 0
+
+	if value := query.Get("limit"); value != "" {
+		num, err := strconv.Atoi(value)
+		if err != nil || num < 1 || num > MaxPageLimit {
+			errs = append(errs, domain.FieldError{Field: "limit", Code: domain.CodeOutOfRange, Message: fmt.Sprintf("must be 1 - %d (got %s)", MaxPageLimit, value)})
+		}
+		limit = num
+	}
+	if value := query.Get("offset"); value != "" {
+		num, err := strconv.Atoi(value)
+		if err != nil || num < 0 {
+			errs = append(errs, domain.FieldError{Field: "offset", Code: domain.CodeOutOfRange, Message: fmt.Sprintf("must be 0 or more (got %s)", value)})
+		}
+		offset = num
+	}
+
+	if len(errs) > 0 {
+		return 0, 0, errs
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/pkg/config"
+)
+
+const maxPriority = 10
+
+// PriorityPolicy computes the kitchen priority of an order and the reasons behind it
+type PriorityPolicy interface {
+	Assign(order domain.Order, at time.Time) (int, []string)
+}
+
+// NewPriorityPolicy builds the policy selected in the 'priority' config section
+func NewPriorityPolicy(cfg config.PriorityConfig) (PriorityPolicy, error) {
+	switch cfg.Policy {
+	case "", "default":
+		return DefaultPriorityPolicy{}, nil
+	case "rules":
+		return NewRulePriorityPolicy(cfg)
+	default:
+		return nil, fmt.Errorf("invalid priority policy: %s", cfg.Policy)
+	}
+}
+
+// DefaultPriorityPolicy keeps the original 10/5/1 thresholds at $100 and $50
+type DefaultPriorityPolicy struct{}
+
+func (DefaultPriorityPolicy) Assign(order domain.Order, at time.Time) (int, []string) {
+	priority, reason := assignDefaultPriority(OrderTotal(order), defaultHighThreshold, defaultMediumThreshold)
+	return priority, []string{reason}
+}
+
+// RulePriorityPolicy starts from the configured thresholds and applies per-restaurant rules
+type RulePriorityPolicy struct {
+	highThreshold    domain.Money
+	mediumThreshold  domain.Money
+	deliveryBonus    int
+	cateringMinItems int
+	cateringCap      int
+	vipCustomers     map[int]bool // registered customer ids
+	vipBonus         int
+	lunchRushStart   time.Duration // offset from midnight
+	lunchRushEnd     time.Duration
+	lunchRushBonus   int
+}
+
+func NewRulePriorityPolicy(cfg config.PriorityConfig) (*RulePriorityPolicy, error) {
+	policy := &RulePriorityPolicy{
+		highThreshold:    defaultHighThreshold,
+		mediumThreshold:  defaultMediumThreshold,
+		deliveryBonus:    cfg.DeliveryBonus,
+		cateringMinItems: cfg.CateringMinItems,
+		cateringCap:      cfg.CateringCap,
+		vipCustomers:     make(map[int]bool),
+		vipBonus:         cfg.VIPBonus,
+		lunchRushBonus:   cfg.LunchRushBonus,
+	}
+
+	var err error
+	if cfg.HighThreshold != "" {
+		if policy.highThreshold, err = domain.ParseMoney(cfg.HighThreshold); err != nil {
+			return nil, fmt.Errorf("invalid priority high_threshold: %w", err)
+		}
+	}
+	if cfg.MediumThreshold != "" {
+		if policy.mediumThreshold, err = domain.ParseMoney(cfg.MediumThreshold); err != nil {
+			return nil, fmt.Errorf("invalid priority medium_threshold: %w", err)
+		}
+	}
+	if policy.highThreshold.LessThan(policy.mediumThreshold) {
+		return nil, fmt.Errorf("invalid priority thresholds: medium %s is above high %s", policy.mediumThreshold, policy.highThreshold)
+	}
+	if policy.cateringCap < 0 || policy.cateringCap > maxPriority {
+		return nil, fmt.Errorf("invalid priority catering_cap: %d", policy.cateringCap)
+	}
+	for _, id := range cfg.VIPCustomers {
+		customerID, err := strconv.Atoi(id)
+		if err != nil || customerID <= 0 {
+			return nil, fmt.Errorf("invalid priority vip_customers: %q is not a customer id", id)
+		}
+		policy.vipCustomers[customerID] = true
+	}
+
+	if cfg.LunchRushStart != "" || cfg.LunchRushEnd != "" {
+		if policy.lunchRushStart, err = parseClock(cfg.LunchRushStart); err != nil {
+			return nil, fmt.Errorf("invalid priority lunch_rush_start: %w", err)
+		}
+		if policy.lunchRushEnd, err = parseClock(cfg.LunchRushEnd); err != nil {
+			return nil, fmt.Errorf("invalid priority lunch_rush_end: %w", err)
+		}
+	}
+	return policy, nil
+}
+
+func (p *RulePriorityPolicy) Assign(order domain.Order, at time.Time) (int, []string) {
+	priority, reason := assignDefaultPriority(OrderTotal(order), p.highThreshold, p.mediumThreshold)
+	reasons := []string{reason}
+
+	if p.deliveryBonus != 0 && order.Type == "delivery" {
+		priority += p.deliveryBonus
+		reasons = append(reasons, fmt.Sprintf("delivery order: %+d", p.deliveryBonus))
+	}
+
+	// Only registered customers can be VIPs, the customer name is free text
+	if p.vipBonus != 0 && order.CustomerID != nil && p.vipCustomers[*order.CustomerID] {
+		priority += p.vipBonus
+		reasons = append(reasons, fmt.Sprintf("VIP customer: %+d", p.vipBonus))
+	}
+
+	if p.lunchRushBonus != 0 && p.lunchRushStart < p.lunchRushEnd {
+		clock := time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+		if clock >= p.lunchRushStart && clock < p.lunchRushEnd {
+			priority += p.lunchRushBonus
+			reasons = append(reasons, fmt.Sprintf("lunch rush: %+d", p.lunchRushBonus))
+		}
+	}
+
+	// Large catering orders must not starve the rest of the kitchen
+	if p.cateringMinItems > 0 && p.cateringCap > 0 {
+		var itemCount int
+		for _, item := range order.Items {
+			itemCount += item.Quantity
+		}
+		if itemCount >= p.cateringMinItems && priority > p.cateringCap {
+			priority = p.cateringCap
+			reasons = append(reasons, fmt.Sprintf("catering order with %d items: capped at %d", itemCount, p.cateringCap))
+		}
+	}
+
+	if priority > maxPriority {
+		priority = maxPriority
+		reasons = append(reasons, fmt.Sprintf("capped at maximum %d", maxPriority))
+	}
+	if priority < 1 {
+		priority = 1
+		reasons = append(reasons, "raised to minimum 1")
+	}
+	return priority, reasons
+}
+
+// parseClock converts "HH:MM" into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"time"
+	"wheres-my-pizza/internal/core/domain"
+	"wheres-my-pizza/pkg/config"
+)
+
+// ScheduleRules validates scheduled_for against business hours and the booking horizon
+type ScheduleRules struct {
+	open    time.Duration // offset from midnight, local time
+	close   time.Duration
+	horizon time.Duration
+	minLead time.Duration
+}
+
+func NewScheduleRules(cfg config.SchedulingConfig) (ScheduleRules, error) {
+	rules := ScheduleRules{
+		horizon: time.Duration(cfg.HorizonHours) * time.Hour,
+		minLead: time.Duration(cfg.MinLeadMinutes) * time.Minute,
+	}
+	var err error
+	if rules.open, err = parseClock(cfg.Open); err != nil {
+		return rules, fmt.Errorf("invalid scheduling open: %w", err)
+	}
+	if rules.close, err = parseClock(cfg.Close); err != nil {
+		return rules, fmt.Errorf("invalid scheduling close: %w", err)
+	}
+	if rules.open >= rules.close {
+		return rules, fmt.Errorf("invalid scheduling hours: open %s is not before close %s", cfg.Open, cfg.Close)
+	}
+	if rules.horizon <= 0 {
+		return rules, fmt.Errorf("invalid scheduling horizon_hours: %d", cfg.HorizonHours)
+	}
+	return rules, nil
+}
+
+// Check returns the field error for scheduled_for or nil for orders that are not scheduled
+func (s ScheduleRules) Check(order domain.Order, now time.Time) *domain.FieldError {
+	if order.ScheduledFor == nil {
+		return nil
+	}
+	scheduledFor := order.ScheduledFor.In(time.Local)
+
+	if scheduledFor.Before(now.Add(s.minLead)) {
+		return &domain.FieldError{Field: "scheduled_for", Code: domain.CodeOutOfRange, Message: fmt.Sprintf("must be at least %s in the future", s.minLead)}
+	}
+	if scheduledFor.After(now.Add(s.horizon)) {
+		return &domain.FieldError{Field: "scheduled_for", Code: domain.CodeOutOfRange, Message: fmt.Sprintf("must be within %s from now", s.horizon)}
+	}
+
+	clock := time.Duration(scheduledFor.Hour())*time.Hour + time.Duration(scheduledFor.Minute())*time.Minute
+	if clock < s.open || clock >= s.close {
+		return &domain.FieldError{Field: "scheduled_for", Code: domain.CodeOutOfRange, Message: fmt.Sprintf("must be within business hours %s - %s", formatClock(s.open), formatClock(s.close))}
+	}
+	return nil
+}
+
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// SplitStations groups the order items by kitchen station, stations are returned in domain.Stations order.
+// An item of an unknown station is an error, it would never be cooked.
+func SplitStations(order domain.Order) ([]string, map[string][]domain.OrderItem, error) {
+	items := make(map[string][]domain.OrderItem)
+	for _, item := range order.Items {
+		if !slices.Contains(domain.Stations, item.Station) {
+			return nil, nil, fmt.Errorf("item %s of order %s has unknown station %q", item.SKU, order.Number, item.Station)
+		}
+		items[item.Station] = append(items[item.Station], item)
+	}
+
+	var stations []string
+	for _, station := range domain.Stations {
+		if len(items[station]) > 0 {
+			stations = append(stations, station)
+		}
+	}
+	return stations, items, nil
+}
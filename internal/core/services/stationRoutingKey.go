@@ -0,0 +1,11 @@
+package services
+
+import (
+	"fmt"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// StationRoutingKey returns the orders_topic routing key of a station ticket: kitchen.{location}.station.{station}
+func StationRoutingKey(order domain.Order, station string) string {
+	return fmt.Sprintf("kitchen.%s.station.%s", order.LocationID, station)
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"sort"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// StockNeeds sums the ingredients of every order item, needs are sorted by ingredient code
+// so that concurrent orders lock the inventory rows in the same order
+func StockNeeds(order domain.Order, recipes map[string][]domain.RecipeLine) []domain.StockNeed {
+	index := make(map[string]int)
+	var needs []domain.StockNeed
+	for i, item := range order.Items {
+		for _, line := range recipes[item.SKU] {
+			j, ok := index[line.IngredientCode]
+			if !ok {
+				j = len(needs)
+				index[line.IngredientCode] = j
+				needs = append(needs, domain.StockNeed{IngredientCode: line.IngredientCode, ItemIndex: i})
+			}
+			needs[j].Quantity += line.Quantity * int64(item.Quantity)
+		}
+	}
+	sort.Slice(needs, func(i, j int) bool { return needs[i].IngredientCode < needs[j].IngredientCode })
+	return needs
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"wheres-my-pizza/internal/core/domain"
+)
+
+// WriteProblem sends an application/problem+json error body
+func WriteProblem(w http.ResponseWriter, status int, detail string, fieldErrors []domain.FieldError) {
+	problem := domain.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Errors: fieldErrors,
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// WriteValidationProblem sends 422 with every field error found in err
+func WriteValidationProblem(w http.ResponseWriter, err error) {
+	WriteProblem(w, http.StatusUnprocessableEntity, "The request failed validation", FieldErrors(err))
+}
+
+// FieldErrors unwraps domain.ValidationErrors, any other error becomes a single field error
+func FieldErrors(err error) []domain.FieldError {
+	var validationErrs domain.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return validationErrs
+	}
+	return []domain.FieldError{{Field: "", Code: domain.CodeInvalid, Message: err.Error()}}
+}
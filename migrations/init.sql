@@ -1,30 +1,263 @@
 
+-- Locations
+create table locations (
+    "id"          text          primary key check (id ~ '^[A-Z0-9]{2,8}$'),
+    "created_at"  timestamptz   not null    default now(),
+    "name"        text          not null
+);
+
+insert into locations (id, name) values
+    ('MAIN',  'Main Street'),
+    ('DT',    'Downtown'),
+    ('HB',    'Harbor');
+
+-- Menu
+create table menu_items (
+    "sku"         text          primary key,
+    "created_at"  timestamptz   not null    default now(),
+    "updated_at"  timestamptz   not null    default now(),
+    "name"        text          not null,
+    "price"       decimal(8,2)  not null    check (price > 0),
+    "available"   boolean       not null    default true,
+    "station"     text          not null    default 'oven' check (station in ('oven', 'cold', 'fryer'))
+);
+
+insert into menu_items (sku, name, price, station) values
+    ('PIZZA-MARG',  'Margherita Pizza',   15.99,  'oven'),
+    ('PIZZA-PEPP',  'Pepperoni Pizza',    18.99,  'oven'),
+    ('PIZZA-QUAT',  'Quattro Formaggi',   19.99,  'oven'),
+    ('SALAD-CAES',  'Caesar Salad',        8.99,  'cold'),
+    ('SIDE-GARL',   'Garlic Bread',        4.99,  'oven'),
+    ('SIDE-FRIES',  'French Fries',        3.99,  'fryer'),
+    ('DRINK-COLA',  'Cola',                2.50,  'cold'),
+    ('DRINK-WATR',  'Mineral Water',       1.99,  'cold');
+
+create table menu_modifiers (
+    "code"         text          primary key,
+    "created_at"   timestamptz   not null    default now(),
+    "updated_at"   timestamptz   not null    default now(),
+    "name"         text          not null,
+    "kind"         text          not null    check (kind in ('size', 'topping', 'extra', 'removal')),
+    "price_delta"  decimal(8,2)  not null    default 0,
+    "available"    boolean       not null    default true
+);
+
+insert into menu_modifiers (code, name, kind, price_delta) values
+    ('SIZE-S',      'Small',          'size',     -2.00),
+    ('SIZE-L',      'Large',          'size',      3.00),
+    ('SIZE-XL',     'Extra Large',    'size',      5.00),
+    ('TOP-CHEESE',  'Extra Cheese',   'topping',   1.50),
+    ('TOP-MUSH',    'Mushrooms',      'topping',   1.25),
+    ('TOP-OLIVE',   'Olives',         'topping',   1.00),
+    ('EXTRA-DIP',   'Garlic Dip',     'extra',     0.99),
+    ('NO-OLIVE',    'No Olives',      'removal',   0.00),
+    ('NO-ONION',    'No Onions',      'removal',   0.00);
+
+-- Inventory: ingredients, recipes of the menu items and the stock of every location
+create table ingredients (
+    "code"        text          primary key,
+    "created_at"  timestamptz   not null    default now(),
+    "name"        text          not null,
+    "unit"        text          not null    check (unit in ('g', 'ml', 'pcs'))
+);
+
+insert into ingredients (code, name, unit) values
+    ('DOUGH',       'Pizza Dough',    'g'),
+    ('TOMATO',      'Tomato Sauce',   'g'),
+    ('MOZZARELLA',  'Mozzarella',     'g'),
+    ('CHEESE-MIX',  'Cheese Mix',     'g'),
+    ('PEPPERONI',   'Pepperoni',      'g'),
+    ('LETTUCE',     'Romaine',        'g'),
+    ('BREAD',       'Baguette',       'pcs'),
+    ('POTATO',      'Potatoes',       'g'),
+    ('COLA',        'Cola Bottle',    'pcs'),
+    ('WATER',       'Water Bottle',   'pcs');
+
+create table recipes (
+    "sku"              text          references menu_items(sku),
+    "ingredient_code"  text          references ingredients(code),
+    "quantity"         integer       not null    check (quantity > 0),
+    primary key (sku, ingredient_code)
+);
+
+insert into recipes (sku, ingredient_code, quantity) values
+    ('PIZZA-MARG',  'DOUGH',       250),
+    ('PIZZA-MARG',  'TOMATO',       80),
+    ('PIZZA-MARG',  'MOZZARELLA',  125),
+    ('PIZZA-PEPP',  'DOUGH',       250),
+    ('PIZZA-PEPP',  'TOMATO',       80),
+    ('PIZZA-PEPP',  'MOZZARELLA',  100),
+    ('PIZZA-PEPP',  'PEPPERONI',    60),
+    ('PIZZA-QUAT',  'DOUGH',       250),
+    ('PIZZA-QUAT',  'MOZZARELLA',   60),
+    ('PIZZA-QUAT',  'CHEESE-MIX',  120),
+    ('SALAD-CAES',  'LETTUCE',     150),
+    ('SALAD-CAES',  'CHEESE-MIX',   20),
+    ('SIDE-GARL',   'BREAD',         1),
+    ('SIDE-FRIES',  'POTATO',      200),
+    ('DRINK-COLA',  'COLA',          1),
+    ('DRINK-WATR',  'WATER',         1);
+
+-- Stock on hand, orders reserve it when they are placed
+create table inventory (
+    "location_id"          text          references locations(id),
+    "ingredient_code"      text          references ingredients(code),
+    "updated_at"           timestamptz   not null    default now(),
+    "quantity"             bigint        not null    check (quantity >= 0),
+    "low_stock_threshold"  bigint        not null    default 0,
+    primary key (location_id, ingredient_code)
+);
+
+insert into inventory (location_id, ingredient_code, quantity, low_stock_threshold)
+select l.id, i.code,
+    case i.unit when 'pcs' then 200 else 50000 end,
+    case i.unit when 'pcs' then 20 else 5000 end
+from locations l cross join ingredients i;
+
+-- Customers
+create table customers (
+    "id"          serial        primary key,
+    "created_at"  timestamptz   not null    default now(),
+    "updated_at"  timestamptz   not null    default now(),
+    "name"        text          not null,
+    "phone"       text          unique,
+    "email"       text          unique,
+    check (phone is not null or email is not null)
+);
+
+-- Dine-in tables and their sessions, a session collects the orders of one tab
+create table dining_tables (
+    "location_id"  text      not null    references locations(id),
+    "number"       integer   not null    check (number between 1 and 100),
+    "seats"        integer   not null    check (seats > 0),
+    "active"       boolean   not null    default true,
+    primary key (location_id, number)
+);
+
+insert into dining_tables (location_id, number, seats)
+select 'MAIN', n, case when n <= 10 then 2 else 4 end from generate_series(1, 20) n;
+insert into dining_tables (location_id, number, seats)
+select loc, n, 4 from unnest(array['DT', 'HB']) loc, generate_series(1, 12) n;
+
+create table table_sessions (
+    "id"            serial        primary key,
+    "location_id"   text          not null,
+    "table_number"  integer       not null,
+    "opened_at"     timestamptz   not null    default now(),
+    "closed_at"     timestamptz,
+    "total_amount"  decimal(10,2),
+    foreign key (location_id, table_number) references dining_tables (location_id, number)
+);
+
+create unique index table_sessions_open_idx on table_sessions (location_id, table_number) where closed_at is null;
+
 -- Orders
 create table "orders" (
-    "id"                serial        primary key,
-    "created_at"        timestamptz   not null    default now(),
-    "updated_at"        timestamptz   not null    default now(),
-    "number"            text          unique not null,
-    "customer_name"     text          not null,
-    "type"              text          not null check (type in ('dine_in', 'takeout', 'delivery')),
-    "table_number"      integer,
-    "delivery_address"  text,
-    "total_amount"      decimal(10,2) not null,
-    "priority"          integer       default 1,
-    "status"            text          default 'received',
-    "processed_by"      text,
-    "completed_at"      timestamptz
+    "id"                  serial        primary key,
+    "created_at"          timestamptz   not null    default now(),
+    "updated_at"          timestamptz   not null    default now(),
+    "number"              text          unique not null,
+    "customer_name"       text          not null,
+    "type"                text          not null check (type in ('dine_in', 'takeout', 'delivery')),
+    "table_number"        integer,
+    "delivery_address"    text,
+    "total_amount"        decimal(10,2) not null,
+    "priority"            integer       default 1,
+    "priority_reasons"    text[],
+    "status"              text          default 'received',
+    "processed_by"        text,
+    "completed_at"        timestamptz,
+    "version"             integer       not null    default 1,
+    "scheduled_for"       timestamptz,
+    "promo_code"          text,
+    "discount_amount"     decimal(10,2) not null    default 0,
+    "customer_id"         integer       references customers(id),
+    "location_id"         text          not null    default 'MAIN' references locations(id),
+    "payment_method"      text          not null    default 'counter' check (payment_method in ('counter', 'online')),
+    "table_session_id"    integer       references table_sessions(id),
+    "estimated_ready_at"  timestamptz
 );
 
+create index orders_customer_idx on orders (customer_id, created_at desc, id desc) where customer_id is not null;
+create index orders_location_idx on orders (location_id, created_at desc);
+create index orders_created_idx on orders (created_at desc, id desc);
+create index orders_priority_idx on orders (priority desc, id desc);
+create index orders_status_created_idx on orders (status, created_at desc, id desc);
+create index orders_processed_by_idx on orders (processed_by, created_at desc) where processed_by is not null;
+create index orders_customer_name_idx on orders (lower(customer_name) text_pattern_ops);
+create index orders_table_session_idx on orders (table_session_id) where table_session_id is not null;
+create index orders_scheduled_idx on orders (scheduled_for) where status = 'scheduled';
+create index orders_completed_idx on orders (location_id, type, completed_at desc) where completed_at is not null;
+
 create table order_items (
     "id"          serial        primary key,
     "created_at"  timestamptz   not null    default now(),
     "order_id"    integer       references orders(id),
+    "sku"         text,
     "name"        text          not null,
     "quantity"    integer       not null,
-    "price"       decimal(8,2)  not null
+    "price"       decimal(8,2)  not null,
+    "station"     text          not null    default 'oven' check (station in ('oven', 'cold', 'fryer'))
+);
+
+create table order_item_modifiers (
+    "id"             serial        primary key,
+    "created_at"     timestamptz   not null    default now(),
+    "order_item_id"  integer       not null    references order_items(id) on delete cascade,
+    "code"           text          not null,
+    "name"           text          not null,
+    "kind"           text          not null,
+    "price_delta"    decimal(8,2)  not null
 );
 
+create index order_item_modifiers_item_idx on order_item_modifiers (order_item_id);
+
+-- Stock taken by an order, given back when the order is cancelled before cooking
+create table stock_reservations (
+    "order_id"         integer       references orders(id),
+    "ingredient_code"  text          references ingredients(code),
+    "created_at"       timestamptz   not null    default now(),
+    "location_id"      text          not null    references locations(id),
+    "quantity"         bigint        not null    check (quantity > 0),
+    primary key (order_id, ingredient_code)
+);
+
+-- Payments: charges of online orders and their refunds
+create table payments (
+    "id"            serial        primary key,
+    "created_at"    timestamptz   not null    default now(),
+    "updated_at"    timestamptz   not null    default now(),
+    "order_id"      integer       not null    references orders(id),
+    "kind"          text          not null    check (kind in ('charge', 'refund')),
+    "provider"      text          not null,
+    "provider_ref"  text,
+    "refund_of"     integer       references payments(id),
+    "amount"        decimal(10,2) not null    check (amount > 0),
+    "status"        text          not null    check (status in ('pending', 'succeeded', 'failed')),
+    "error"         text
+);
+
+create index payments_order_idx on payments (order_id);
+-- Every charge is refunded at most once, failed refunds are retried in place
+create unique index payments_refund_once_idx on payments (refund_of) where kind = 'refund';
+create index payments_refund_retry_idx on payments (updated_at) where kind = 'refund' and status in ('pending', 'failed');
+
+-- Kitchen stations: every order is split into one ticket per station of its items
+create table order_station_tickets (
+    "id"             serial        primary key,
+    "created_at"     timestamptz   not null    default now(),
+    "updated_at"     timestamptz   not null    default now(),
+    "order_id"       integer       not null    references orders(id),
+    "order_version"  integer       not null,
+    "station"        text          not null    check (station in ('oven', 'cold', 'fryer')),
+    "status"         text          not null    default 'pending' check (status in ('pending', 'cooking', 'done', 'superseded')),
+    "worker"         text,
+    "started_at"     timestamptz,
+    "done_at"        timestamptz
+);
+
+create index order_station_tickets_order_idx on order_station_tickets (order_id, order_version);
+
 create table order_status_log (
     "id"          serial        primary key,
     "created_at"  timestamptz   not null    default now(),
@@ -36,8 +269,10 @@ create table order_status_log (
 );
 
 create table if not exists order_number_seq (
-  day date PRIMARY KEY,
-  seq bigint NOT NULL
+  location_id text NOT NULL DEFAULT 'MAIN' REFERENCES locations(id),
+  day date NOT NULL,
+  seq bigint NOT NULL,
+  PRIMARY KEY (location_id, day)
 );
 
 -- Kitchen
@@ -48,5 +283,87 @@ create table workers (
     "type"              text        not null,
     "status"            text        default 'online',
     "last_seen"         timestamptz default current_timestamp,
-    "orders_processed"  integer     default 0
+    "orders_processed"  integer     default 0,
+    "location_id"       text        not null    default 'MAIN' references locations(id),
+    "stations"          text        not null    default 'oven,cold,fryer'
+);
+
+-- Delivery couriers, registered like kitchen workers
+create table couriers (
+    "id"                    serial      primary key,
+    "created_at"            timestamptz not null    default now(),
+    "name"                  text        unique not null,
+    "status"                text        default 'online',
+    "last_seen"             timestamptz default current_timestamp,
+    "deliveries_completed"  integer     default 0,
+    "location_id"           text        not null    default 'MAIN' references locations(id)
 );
+
+create table idempotency_keys (
+    "key"           text          primary key,
+    "created_at"    timestamptz   not null    default now(),
+    "request_hash"  text          not null,
+    "order_id"      integer       references orders(id),
+    "response"      jsonb
+);
+
+-- API keys of the order and tracking services, only the SHA-256 of a key is stored
+create table api_keys (
+    "id"          serial        primary key,
+    "created_at"  timestamptz   not null    default now(),
+    "name"        text          not null,
+    "key_hash"    text          unique not null,
+    "scopes"      text[]        not null    default '{}',
+    "revoked_at"  timestamptz
+);
+
+-- Outbox for messages that must be published after the order transaction commits
+create table outbox (
+    "id"                serial        primary key,
+    "created_at"        timestamptz   not null    default now(),
+    "order_id"          integer       references orders(id),
+    "exchange"          text          not null,
+    "routing_key"       text          not null,
+    "payload"           jsonb         not null,
+    "priority"          integer       not null    default 0,
+    "status"            text          not null    default 'pending' check (status in ('pending', 'sent')),
+    "attempts"          integer       not null    default 0,
+    "next_attempt_at"   timestamptz   not null    default now(),
+    "last_error"        text,
+    "sent_at"           timestamptz
+);
+
+create index outbox_pending_idx on outbox (next_attempt_at) where status = 'pending';
+
+-- Promotions
+create table promotions (
+    "code"            text          primary key,
+    "created_at"      timestamptz   not null    default now(),
+    "description"     text          not null    default '',
+    "rule_type"       text          not null    check (rule_type in ('percent_off', 'amount_off', 'bundle_price')),
+    "percent"         decimal(5,2),             -- percent_off
+    "amount"          decimal(10,2),            -- amount_off: discount, bundle_price: price of one bundle
+    "sku_prefix"      text,                     -- bundle_price: items that form a bundle
+    "bundle_quantity" integer,                  -- bundle_price: units in one bundle
+    "order_type"      text,                     -- nullable, only orders of this type
+    "weekday"         integer       check (weekday between 0 and 6), -- nullable, 0 = Sunday
+    "min_total"       decimal(10,2) not null    default 0,
+    "valid_from"      timestamptz   not null    default now(),
+    "valid_until"     timestamptz,
+    "max_uses"        integer,                  -- nullable, unlimited
+    "uses"            integer       not null    default 0,
+    "active"          boolean       not null    default true
+);
+
+create table promotion_redemptions (
+    "id"              serial        primary key,
+    "created_at"      timestamptz   not null    default now(),
+    "promo_code"      text          references promotions(code),
+    "order_id"        integer       unique references orders(id),
+    "discount_amount" decimal(10,2) not null
+);
+
+insert into promotions (code, description, rule_type, amount, sku_prefix, bundle_quantity) values
+    ('TWOPIZZAS25', '2 pizzas for $25', 'bundle_price', 25.00, 'PIZZA-', 2);
+insert into promotions (code, description, rule_type, percent, order_type, weekday) values
+    ('TUESDAYDELIVERY', '10% off delivery on Tuesdays', 'percent_off', 10, 'delivery', 2);
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -21,6 +22,55 @@ type Config struct {
 		User     string
 		Password string
 	}
+	Priority   PriorityConfig
+	Scheduling SchedulingConfig
+	Spool      SpoolConfig
+	Payments   PaymentsConfig
+	Auth       AuthConfig
+}
+
+// minJWTSecretLen is the shortest HS256 key accepted, shorter keys can be brute forced
+const minJWTSecretLen = 32
+
+// AuthConfig protects the HTTP services with API keys and HMAC signed bearer tokens
+type AuthConfig struct {
+	Enabled   bool
+	JWTSecret string // HS256 key of bearer tokens, tokens are rejected when empty
+}
+
+// PaymentsConfig selects the payment provider of online orders
+type PaymentsConfig struct {
+	Provider string // "fake"
+}
+
+// SpoolConfig bounds the local disk spool used while RabbitMQ is unreachable
+type SpoolConfig struct {
+	Dir           string
+	MaxMB         int
+	MaxAgeMinutes int
+}
+
+// SchedulingConfig limits when scheduled orders can be picked up
+type SchedulingConfig struct {
+	Open           string // HH:MM, local time
+	Close          string // HH:MM, local time
+	HorizonHours   int
+	MinLeadMinutes int
+}
+
+// PriorityConfig selects and tunes the order priority policy
+type PriorityConfig struct {
+	Policy           string // "default" or "rules"
+	HighThreshold    string // decimal amount, e.g. 100.00
+	MediumThreshold  string
+	DeliveryBonus    int
+	CateringMinItems int
+	CateringCap      int
+	VIPCustomers     []string // customer ids
+	VIPBonus         int
+	LunchRushStart   string // HH:MM
+	LunchRushEnd     string // HH:MM
+	LunchRushBonus   int
 }
 
 var path string = "config.yaml"
@@ -33,6 +83,10 @@ func LoadConfig() (*Config, error) {
 	defer file.Close()
 
 	cfg := &Config{}
+	cfg.Scheduling = SchedulingConfig{Open: "10:00", Close: "22:00", HorizonHours: 72, MinLeadMinutes: 15}
+	cfg.Spool = SpoolConfig{Dir: "spool", MaxMB: 64, MaxAgeMinutes: 60}
+	cfg.Payments = PaymentsConfig{Provider: "fake"}
+	cfg.Auth = AuthConfig{Enabled: true}
 	scanner := bufio.NewScanner(file)
 
 	section := ""
@@ -44,7 +98,7 @@ func LoadConfig() (*Config, error) {
 			continue
 		}
 
-		// Section headers (database:, rabbitmq:)
+		// Section headers (database:, rabbitmq:, priority:, scheduling:, spool:, payments:, auth:)
 		if strings.HasSuffix(line, ":") && !strings.Contains(line, " ") {
 			section = strings.TrimSuffix(line, ":")
 			continue
@@ -87,6 +141,81 @@ func LoadConfig() (*Config, error) {
 			case "password":
 				cfg.RabbitMQ.Password = val
 			}
+		case "priority":
+			switch key {
+			case "policy":
+				cfg.Priority.Policy = val
+			case "high_threshold":
+				cfg.Priority.HighThreshold = val
+			case "medium_threshold":
+				cfg.Priority.MediumThreshold = val
+			case "delivery_bonus":
+				num, _ := strconv.Atoi(val)
+				cfg.Priority.DeliveryBonus = num
+			case "catering_min_items":
+				num, _ := strconv.Atoi(val)
+				cfg.Priority.CateringMinItems = num
+			case "catering_cap":
+				num, _ := strconv.Atoi(val)
+				cfg.Priority.CateringCap = num
+			case "vip_customers":
+				for _, name := range strings.Split(val, ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						cfg.Priority.VIPCustomers = append(cfg.Priority.VIPCustomers, name)
+					}
+				}
+			case "vip_bonus":
+				num, _ := strconv.Atoi(val)
+				cfg.Priority.VIPBonus = num
+			case "lunch_rush_start":
+				cfg.Priority.LunchRushStart = val
+			case "lunch_rush_end":
+				cfg.Priority.LunchRushEnd = val
+			case "lunch_rush_bonus":
+				num, _ := strconv.Atoi(val)
+				cfg.Priority.LunchRushBonus = num
+			}
+		case "scheduling":
+			switch key {
+			case "open":
+				cfg.Scheduling.Open = val
+			case "close":
+				cfg.Scheduling.Close = val
+			case "horizon_hours":
+				num, _ := strconv.Atoi(val)
+				cfg.Scheduling.HorizonHours = num
+			case "min_lead_minutes":
+				num, _ := strconv.Atoi(val)
+				cfg.Scheduling.MinLeadMinutes = num
+			}
+		case "spool":
+			switch key {
+			case "dir":
+				cfg.Spool.Dir = val
+			case "max_mb":
+				num, _ := strconv.Atoi(val)
+				cfg.Spool.MaxMB = num
+			case "max_age_minutes":
+				num, _ := strconv.Atoi(val)
+				cfg.Spool.MaxAgeMinutes = num
+			}
+		case "payments":
+			switch key {
+			case "provider":
+				cfg.Payments.Provider = val
+			}
+		case "auth":
+			switch key {
+			case "enabled":
+				// A typo must not turn authentication off
+				enabled, err := strconv.ParseBool(val)
+				if err != nil {
+					return nil, fmt.Errorf("invalid auth enabled: %q", val)
+				}
+				cfg.Auth.Enabled = enabled
+			case "jwt_secret":
+				cfg.Auth.JWTSecret = val
+			}
 		}
 	}
 
@@ -94,5 +223,9 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if secret := cfg.Auth.JWTSecret; secret != "" && (secret == "change-me-in-production" || len(secret) < minJWTSecretLen) {
+		return nil, fmt.Errorf("invalid auth jwt_secret: must be a random key of at least %d bytes", minJWTSecretLen)
+	}
+
 	return cfg, nil
 }
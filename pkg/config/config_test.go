@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadTestConfig(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := path
+	path = file
+	t.Cleanup(func() { path = old })
+	return LoadConfig()
+}
+
+func TestLoadConfigAuth(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name        string
+		yaml        string
+		wantErr     bool
+		wantEnabled bool
+		wantSecret  string
+	}{
+		{name: "no auth section", yaml: "payments:\n  provider: fake\n", wantEnabled: true},
+		{name: "enabled", yaml: "auth:\n  enabled: true\n  jwt_secret: " + secret + "\n", wantEnabled: true, wantSecret: secret},
+		{name: "disabled", yaml: "auth:\n  enabled: false\n", wantEnabled: false},
+		{name: "api keys only", yaml: "auth:\n  enabled: true\n  # jwt_secret: <random key>\n", wantEnabled: true},
+		{name: "typo in enabled", yaml: "auth:\n  enabled: flase\n", wantErr: true},
+		{name: "inline comment", yaml: "auth:\n  enabled: false # for local tests\n", wantErr: true},
+		{name: "empty enabled keeps auth on", yaml: "auth:\n  enabled: \n", wantEnabled: true},
+		{name: "placeholder secret", yaml: "auth:\n  jwt_secret: change-me-in-production\n", wantErr: true},
+		{name: "short secret", yaml: "auth:\n  jwt_secret: 0123456789abcdef0123456789abcde\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTestConfig(t, tt.yaml)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadConfig() = %+v, want an error", cfg.Auth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if cfg.Auth.Enabled != tt.wantEnabled || cfg.Auth.JWTSecret != tt.wantSecret {
+				t.Errorf("LoadConfig() auth = %+v, want enabled %v secret %q", cfg.Auth, tt.wantEnabled, tt.wantSecret)
+			}
+		})
+	}
+}
+
+// The shipped config must load, auth is enabled there by default
+func TestLoadConfigShipped(t *testing.T) {
+	old := path
+	path = filepath.Join("..", "..", "config.yaml")
+	t.Cleanup(func() { path = old })
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !cfg.Auth.Enabled || cfg.Auth.JWTSecret != "" {
+		t.Errorf("LoadConfig() auth = %+v, want enabled without a secret", cfg.Auth)
+	}
+}